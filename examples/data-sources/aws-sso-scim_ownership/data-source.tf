@@ -0,0 +1,3 @@
+data "aws-sso-scim_ownership" "example" {
+  ownership_prefix = "terraform:"
+}
@@ -0,0 +1,5 @@
+data "aws-sso-scim_request" "example" {
+  path       = "Users"
+  filter     = "userName eq \"john.doe@example.com\""
+  attributes = ["userName", "title"]
+}
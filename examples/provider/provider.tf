@@ -1,4 +1,16 @@
 provider "aws-sso-scim" {
   endpoint = "https://scim.eu-central-1.amazonaws.com/<someid>/scim/v2/"
   token    = "***"
+
+  user_defaults {
+    email_type = "work"
+    active     = true
+  }
+
+  instances {
+    name       = "staging"
+    region     = "eu-west-1"
+    tenant_id  = "<otherid>"
+    token_file = "/run/secrets/staging-scim-token"
+  }
 }
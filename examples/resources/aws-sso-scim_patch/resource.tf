@@ -0,0 +1,16 @@
+data "aws-sso-scim_user" "example" {
+  user_name = "foo"
+}
+
+resource "aws-sso-scim_patch" "example" {
+  resource_type = "Users"
+  resource_id   = data.aws-sso-scim_user.example.id
+
+  operations = jsonencode([
+    {
+      op    = "replace"
+      path  = "title"
+      value = "Engineer"
+    }
+  ])
+}
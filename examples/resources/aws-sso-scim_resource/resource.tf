@@ -0,0 +1,9 @@
+resource "aws-sso-scim_resource" "example" {
+  endpoint = "Devices"
+  schemas  = ["urn:example:params:scim:schemas:core:2.0:Device"]
+
+  attributes = jsonencode({
+    displayName  = "build-agent-01"
+    serialNumber = "SN-0001"
+  })
+}
@@ -0,0 +1,208 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// environment variables describing the Terraform run, recorded in the audit log
+var auditEnvironment = []string{
+	"TF_WORKSPACE",
+	"TFC_RUN_ID",
+	"TFC_WORKSPACE_NAME",
+	"TFC_WORKSPACE_SLUG",
+	"TFC_CONFIGURATION_VERSION_GIT_BRANCH",
+	"TFC_CONFIGURATION_VERSION_GIT_COMMIT_SHA",
+	"ATLAS_RUN_ID",
+	"CI_PIPELINE_ID",
+	"GITHUB_RUN_ID",
+}
+
+// AuditLog appends a JSON line per mutating request to a file. It is shared by the clients of all instances.
+type AuditLog struct {
+	mu        sync.Mutex
+	file      *os.File
+	terraform map[string]string
+}
+
+type auditRecord struct {
+	Time                 string            `json:"time"`
+	Endpoint             string            `json:"endpoint"`
+	Method               string            `json:"method"`
+	Path                 string            `json:"path"`
+	ObjectID             string            `json:"object_id,omitempty"`
+	NaturalKey           string            `json:"natural_key,omitempty"`
+	Body                 interface{}       `json:"body,omitempty"`
+	Status               int               `json:"status,omitempty"`
+	Error                string            `json:"error,omitempty"`
+	DurationMs           int64             `json:"duration_ms"`
+	FreezeWindow         string            `json:"freeze_window,omitempty"`
+	FreezeOverrideReason string            `json:"freeze_override_reason,omitempty"`
+	Terraform            map[string]string `json:"terraform,omitempty"`
+}
+
+func NewAuditLog(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit_log_file: %v", err)
+	}
+
+	return &AuditLog{file: file, terraform: terraformMetadata()}, nil
+}
+
+// terraformMetadata returns the variables of auditEnvironment which are set.
+func terraformMetadata() map[string]string {
+	terraform := map[string]string{}
+	for _, k := range auditEnvironment {
+		if v := os.Getenv(k); v != "" {
+			terraform[k] = v
+		}
+	}
+	return terraform
+}
+
+// write appends record as a single line, so concurrent records don't interleave.
+func (a *AuditLog) write(record auditRecord) error {
+	record.Terraform = a.terraform
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, err = a.file.Write(append(line, '\n'))
+	return err
+}
+
+// audit records a mutating request which took duration. The body is always redacted. existingKey is the natural
+// key of the object read before the request, see existingNaturalKey, otherwise the one in body is recorded.
+func (c *APIClient) audit(method, path string, body interface{}, existingKey string, v interface{}, resp *http.Response, reqErr error, duration time.Duration) {
+	if c.AuditLog == nil || !isMutatingMethod(method) {
+		return
+	}
+
+	key := c.auditNaturalKey(path, body)
+	if existingKey != "" {
+		key = c.redactNaturalKey(path, existingKey)
+	}
+
+	record := auditRecord{
+		Time:       time.Now().UTC().Format(time.RFC3339Nano),
+		Endpoint:   c.BaseURL.String(),
+		Method:     method,
+		Path:       path,
+		ObjectID:   auditObjectID(path, v),
+		NaturalKey: key,
+		Body:       redactPII(path, body),
+		DurationMs: duration.Milliseconds(),
+	}
+
+	if resp != nil {
+		record.Status = resp.StatusCode
+	}
+	if reqErr != nil {
+		record.Error = reqErr.Error()
+	}
+
+	if w, _, active := c.activeFreezeWindow(time.Now()); active {
+		record.FreezeWindow = w.Name
+		record.FreezeOverrideReason = c.FreezeOverrideReason
+	}
+
+	if err := c.AuditLog.write(record); err != nil {
+		c.diags.add(diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Unable to write audit log",
+			Detail:   fmt.Sprintf("%v %v has not been recorded in audit_log_file: %v", method, path, err),
+		})
+	}
+}
+
+// auditObjectID returns the ID of the object from path like "Users/1234", or for created objects from the response v.
+func auditObjectID(path string, v interface{}) string {
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	if parts := strings.Split(path, "/"); len(parts) >= 2 {
+		return parts[1]
+	}
+
+	if v == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(encoded, &object)
+	return object.ID
+}
+
+// auditNaturalKey returns the natural key of the object in body, which is redacted for users if SensitivePIIEnv is set.
+func (c *APIClient) auditNaturalKey(path string, body interface{}) string {
+	return c.redactNaturalKey(path, naturalKey(body))
+}
+
+func (c *APIClient) redactNaturalKey(path string, key string) string {
+	if key != "" && c.SensitivePII && strings.HasPrefix(path, "Users") {
+		return "<redacted>"
+	}
+	return key
+}
+
+// existingNaturalKey reads the natural key of the user or group at path before a request whose body doesn't
+// contain it, e.g. a DELETE or a PATCH of group members, so the audit log tells which object it was.
+func (c *APIClient) existingNaturalKey(method, path string, body interface{}) string {
+	if c.AuditLog == nil || (method != "DELETE" && method != "PATCH") || naturalKey(body) != "" {
+		return ""
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || !isOwnedEndpoint(parts[0]) || parts[1] == "" {
+		return ""
+	}
+
+	var object map[string]interface{}
+	if _, err := c.doRequest("GET", path, "", nil, &object); err != nil {
+		return ""
+	}
+	return naturalKey(object)
+}
+
+// naturalKey returns the externalId, userName or displayName of the object in body.
+func naturalKey(body interface{}) string {
+	if body == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return ""
+	}
+	var object struct {
+		ExternalID  string `json:"externalId"`
+		UserName    string `json:"userName"`
+		DisplayName string `json:"displayName"`
+	}
+	json.Unmarshal(encoded, &object)
+
+	switch {
+	case object.ExternalID != "":
+		return object.ExternalID
+	case object.UserName != "":
+		return object.UserName
+	}
+	return object.DisplayName
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readAuditLog(t *testing.T, path string) []auditRecord {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	records := []auditRecord{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAuditRecordsNaturalKeysOfDeletedAndPatchedObjects(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/Users/1":
+			fmt.Fprint(w, `{"id":"1","userName":"jdoe"}`)
+		case r.Method == "GET" && r.URL.Path == "/Groups/2":
+			fmt.Fprint(w, `{"id":"2","displayName":"admins"}`)
+		default:
+			w.WriteHeader(204)
+		}
+	})
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AuditLog = auditLog
+
+	if _, err := c.DeleteUser("1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddGroupMember("2", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readAuditLog(t, path)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %v", records)
+	}
+	if records[0].Method != "DELETE" || records[0].NaturalKey != "jdoe" {
+		t.Errorf("expected the deletion of jdoe to be recorded, got %+v", records[0])
+	}
+	if records[1].Method != "PATCH" || records[1].NaturalKey != "admins" {
+		t.Errorf("expected the change of admins to be recorded, got %+v", records[1])
+	}
+
+	c.SensitivePII = true
+	c.DeleteUser("1")
+	if records := readAuditLog(t, path); records[2].NaturalKey != "<redacted>" {
+		t.Errorf("expected the user name to be redacted, got %+v", records[2])
+	}
+}
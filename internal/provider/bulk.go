@@ -0,0 +1,330 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	bulkRequestSchema = "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+
+	// prefix used to reference the result of an earlier operation within the same bulk request
+	bulkIDPrefix = "bulkId:"
+)
+
+// BulkResult is the outcome of a single BulkOperation, in the order the operations were given.
+type BulkResult struct {
+	Method     string
+	BulkID     string
+	Path       string
+	ID         string
+	Location   string
+	StatusCode int
+	Response   json.RawMessage
+	Err        error
+}
+
+// ServiceProviderConfig is fetched once per client. Failures are not kept, so the next call tries again.
+func (c *APIClient) ServiceProviderConfig() (*ServiceProviderConfig, error) {
+	c.spcMu.Lock()
+	defer c.spcMu.Unlock()
+
+	if c.spc != nil {
+		return c.spc, nil
+	}
+
+	var spc ServiceProviderConfig
+	if _, err := c.doRequest("GET", "ServiceProviderConfig", "", nil, &spc); err != nil {
+		return nil, err
+	}
+	c.spc = &spc
+
+	return c.spc, nil
+}
+
+// Bulk sends all operations to the /Bulk endpoint if the server advertises support for it.
+// Otherwise the operations are run one after another, so every one of them passes the rate limiter.
+// In both cases "bulkId:<id>" references in paths and data are resolved to the IDs of created resources.
+// failOnErrors stops processing after that many failed operations, 0 means never stop.
+func (c *APIClient) Bulk(ops []BulkOperation, failOnErrors int) ([]BulkResult, error) {
+	ops, err := c.stampBulkOperations(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	spc, err := c.ServiceProviderConfig()
+	if err != nil || !spc.Bulk.Supported {
+		return c.bulkSequential(ops, failOnErrors, map[string]string{}, 0)
+	}
+
+	chunkSize := len(ops)
+	if spc.Bulk.MaxOperations > 0 && spc.Bulk.MaxOperations < chunkSize {
+		chunkSize = spc.Bulk.MaxOperations
+	}
+
+	results := make([]BulkResult, 0, len(ops))
+	ids := map[string]string{}
+	errorCount := 0
+
+	for start := 0; start < len(ops); start += chunkSize {
+		end := start + chunkSize
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		// references to operations of previous chunks can not be resolved by the server anymore
+		chunk := make([]BulkOperation, 0, end-start)
+		for _, op := range ops[start:end] {
+			resolved, err := resolveBulkIDs(op, ids)
+			if err != nil {
+				return results, err
+			}
+			chunk = append(chunk, resolved)
+		}
+
+		remaining := 0
+		if failOnErrors > 0 {
+			remaining = failOnErrors - errorCount
+		}
+
+		chunkResults, resp, err := c.bulkRequest(chunk, remaining)
+		if err != nil {
+			// the server advertised bulk support, but doesn't provide the endpoint
+			if resp != nil && resp.StatusCode == 404 {
+				// the operations of this chunk have been checked already
+				rest, err := c.bulkSequential(ops[start:], remaining, ids, end-start)
+				return append(results, rest...), err
+			}
+			return results, err
+		}
+
+		for _, r := range chunkResults {
+			if r.Err != nil {
+				errorCount++
+			}
+			if r.BulkID != "" && r.ID != "" {
+				ids[r.BulkID] = r.ID
+			}
+		}
+		results = append(results, chunkResults...)
+
+		if failOnErrors > 0 && errorCount >= failOnErrors {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func (c *APIClient) bulkRequest(ops []BulkOperation, failOnErrors int) ([]BulkResult, *http.Response, error) {
+	bulkReq := BulkRequest{
+		Schemas:      []string{bulkRequestSchema},
+		FailOnErrors: failOnErrors,
+		Operations:   ops,
+	}
+
+	var bulkResp BulkResponse
+	resp, err := c.doRequest("POST", "Bulk", "", bulkReq, &bulkResp)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	// responses are matched by bulkId where possible and by position otherwise
+	byBulkID := map[string]BulkOperationResponse{}
+	for _, r := range bulkResp.Operations {
+		if r.BulkID != "" {
+			byBulkID[r.BulkID] = r
+		}
+	}
+
+	results := make([]BulkResult, 0, len(bulkResp.Operations))
+	for i, op := range ops {
+		var opResp BulkOperationResponse
+		var ok bool
+
+		if op.BulkID != "" {
+			opResp, ok = byBulkID[op.BulkID]
+		} else if i < len(bulkResp.Operations) && bulkResp.Operations[i].BulkID == "" {
+			opResp, ok = bulkResp.Operations[i], true
+		}
+
+		if !ok {
+			// the server stopped processing because failOnErrors was reached
+			continue
+		}
+
+		results = append(results, newBulkResult(op, opResp))
+	}
+
+	return results, resp, nil
+}
+
+func newBulkResult(op BulkOperation, opResp BulkOperationResponse) BulkResult {
+	result := BulkResult{
+		Method:   op.Method,
+		BulkID:   op.BulkID,
+		Path:     op.Path,
+		Location: opResp.Location,
+		Response: opResp.Response,
+	}
+
+	status, err := strconv.Atoi(opResp.Status)
+	if err != nil {
+		result.Err = fmt.Errorf("invalid status %q in bulk response", opResp.Status)
+		return result
+	}
+	result.StatusCode = status
+
+	if status < 200 || status > 299 {
+		var scimErr struct {
+			Detail string `json:"detail"`
+		}
+		json.Unmarshal(opResp.Response, &scimErr)
+		result.Err = fmt.Errorf("unexpected HTTP status code: %v %v", status, scimErr.Detail)
+		return result
+	}
+
+	if opResp.Location != "" {
+		result.ID = opResp.Location[strings.LastIndex(opResp.Location, "/")+1:]
+	}
+
+	return result
+}
+
+// bulkSequential runs the operations one after another. Each operation passes the checks of single requests,
+// except for the first checked ones.
+func (c *APIClient) bulkSequential(ops []BulkOperation, failOnErrors int, ids map[string]string, checked int) ([]BulkResult, error) {
+	results := make([]BulkResult, 0, len(ops))
+	errorCount := 0
+
+	for i, op := range ops {
+		resolved, err := resolveBulkIDs(op, ids)
+		if err != nil {
+			return results, err
+		}
+
+		result := BulkResult{
+			Method: op.Method,
+			BulkID: op.BulkID,
+			Path:   resolved.Path,
+		}
+
+		// the referenced operation failed or comes later, so there is nothing to point to
+		if hasBulkIDReference(resolved) {
+			result.Err = fmt.Errorf("unresolved bulkId reference in %v %v", op.Method, op.Path)
+			results = append(results, result)
+			errorCount++
+			if failOnErrors > 0 && errorCount >= failOnErrors {
+				break
+			}
+			continue
+		}
+
+		if i >= checked {
+			if err := c.refuseBulkOperation(resolved); err != nil {
+				result.Err = err
+				results = append(results, result)
+				errorCount++
+				if failOnErrors > 0 && errorCount >= failOnErrors {
+					break
+				}
+				continue
+			}
+		}
+
+		var body interface{}
+		if resolved.Data != nil {
+			body = resolved.Data
+		}
+
+		var response json.RawMessage
+		resp, err := c.doRequest(resolved.Method, strings.TrimPrefix(resolved.Path, "/"), "", body, &response)
+		if resp != nil {
+			result.StatusCode = resp.StatusCode
+			result.Location = resp.Header.Get("Location")
+		}
+		result.Response = response
+		result.Err = err
+
+		if err == nil && len(response) > 0 {
+			var created struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(response, &created) == nil {
+				result.ID = created.ID
+			}
+		}
+		if result.ID == "" && resolved.Method != "POST" {
+			result.ID = resolved.Path[strings.LastIndex(resolved.Path, "/")+1:]
+		}
+
+		if op.BulkID != "" && result.ID != "" {
+			ids[op.BulkID] = result.ID
+		}
+
+		results = append(results, result)
+
+		if err != nil {
+			errorCount++
+			if failOnErrors > 0 && errorCount >= failOnErrors {
+				break
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// hasBulkIDReference reports whether path or data still reference the result of another operation.
+func hasBulkIDReference(op BulkOperation) bool {
+	if strings.Contains(op.Path, bulkIDPrefix) {
+		return true
+	}
+
+	data, err := json.Marshal(op.Data)
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(string(data), fmt.Sprintf("\"%v", bulkIDPrefix))
+}
+
+// a whole "bulkId:<id>" reference within a path, ending at the next "/" or the end of the path
+var bulkIDPathReference = regexp.MustCompile(bulkIDPrefix + `[^/?]+`)
+
+// resolveBulkIDs replaces "bulkId:<id>" references in path and data by the IDs of already created resources.
+// References which are not known yet are left untouched.
+func resolveBulkIDs(op BulkOperation, ids map[string]string) (BulkOperation, error) {
+	if len(ids) == 0 {
+		return op, nil
+	}
+
+	op.Path = bulkIDPathReference.ReplaceAllStringFunc(op.Path, func(ref string) string {
+		if id, ok := ids[strings.TrimPrefix(ref, bulkIDPrefix)]; ok {
+			return id
+		}
+		return ref
+	})
+
+	if op.Data == nil {
+		return op, nil
+	}
+
+	data, err := json.Marshal(op.Data)
+	if err != nil {
+		return op, err
+	}
+
+	replaced := string(data)
+	for bulkID, id := range ids {
+		replaced = strings.ReplaceAll(replaced, fmt.Sprintf("%q", bulkIDPrefix+bulkID), fmt.Sprintf("%q", id))
+	}
+
+	op.Data = json.RawMessage(replaced)
+
+	return op, nil
+}
@@ -0,0 +1,253 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// newTestClient returns a client of a local SCIM server answering with handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *APIClient {
+	t.Helper()
+
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(server.URL, NewStaticTokenSource("token"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetTransport(server.Client().Transport)
+
+	return c
+}
+
+func TestResolveBulkIDs(t *testing.T) {
+	ids := map[string]string{"1": "abc", "user": "u-1"}
+
+	cases := []struct {
+		path string
+		data interface{}
+
+		expectedPath string
+		expectedData string
+	}{
+		{path: "/Groups/bulkId:1", expectedPath: "/Groups/abc"},
+		{path: "/Groups/bulkId:10", expectedPath: "/Groups/bulkId:10"},
+		{path: "/Groups/bulkId:1/members", expectedPath: "/Groups/abc/members"},
+		{path: "/Groups/bulkId:2", expectedPath: "/Groups/bulkId:2"},
+		{
+			path:         "/Groups/g-1",
+			data:         map[string]interface{}{"members": []map[string]string{{"value": "bulkId:user"}, {"value": "bulkId:user2"}}},
+			expectedPath: "/Groups/g-1",
+			expectedData: `{"members":[{"value":"u-1"},{"value":"bulkId:user2"}]}`,
+		},
+	}
+
+	for _, tc := range cases {
+		resolved, err := resolveBulkIDs(BulkOperation{Method: "PATCH", Path: tc.path, Data: tc.data}, ids)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.path, err)
+		}
+		if resolved.Path != tc.expectedPath {
+			t.Errorf("%v: expected path %q, got %q", tc.path, tc.expectedPath, resolved.Path)
+		}
+		if tc.expectedData != "" {
+			data, _ := json.Marshal(resolved.Data)
+			if string(data) != tc.expectedData {
+				t.Errorf("%v: expected data %v, got %s", tc.path, tc.expectedData, data)
+			}
+		}
+	}
+}
+
+// bulkServer answers bulk requests with one 201 per operation, whose IDs are the bulkIds prefixed with "id-".
+// Its responses are in reverse order, so they have to be matched by bulkId.
+type bulkServer struct {
+	mu            sync.Mutex
+	maxOperations int
+	requests      []BulkRequest
+}
+
+func (s *bulkServer) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/ServiceProviderConfig":
+		fmt.Fprintf(w, `{"bulk":{"supported":true,"maxOperations":%v}}`, s.maxOperations)
+	case "/Bulk":
+		var req BulkRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+
+		resp := BulkResponse{}
+		for i := len(req.Operations) - 1; i >= 0; i-- {
+			op := req.Operations[i]
+			resp.Operations = append(resp.Operations, BulkOperationResponse{
+				Method:   op.Method,
+				BulkID:   op.BulkID,
+				Location: "https://example.com/Groups/id-" + op.BulkID,
+				Status:   "201",
+			})
+		}
+		json.NewEncoder(w).Encode(resp)
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func TestBulkChunking(t *testing.T) {
+	server := &bulkServer{maxOperations: 2}
+	c := newTestClient(t, server.handle)
+
+	ops := []BulkOperation{
+		{Method: "POST", BulkID: "a", Path: "/Groups", Data: map[string]string{"displayName": "a"}},
+		{Method: "POST", BulkID: "b", Path: "/Groups", Data: map[string]string{"displayName": "b"}},
+		{Method: "POST", BulkID: "c", Path: "/Groups", Data: map[string]string{"displayName": "c"}},
+		{Method: "PATCH", BulkID: "d", Path: "/Groups/bulkId:a"},
+		{Method: "PATCH", BulkID: "e", Path: "/Groups/bulkId:c"},
+	}
+
+	results, err := c.Bulk(ops, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.requests) != 3 {
+		t.Fatalf("expected 3 bulk requests, got %v", len(server.requests))
+	}
+
+	// references to operations of earlier chunks are resolved before sending
+	if path := server.requests[1].Operations[1].Path; path != "/Groups/id-a" {
+		t.Errorf("expected reference to earlier chunk to be resolved, got %q", path)
+	}
+	if path := server.requests[2].Operations[0].Path; path != "/Groups/id-c" {
+		t.Errorf("expected reference to earlier chunk to be resolved, got %q", path)
+	}
+
+	ids := []string{}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("%v %v: %v", r.Method, r.Path, r.Err)
+		}
+		ids = append(ids, r.ID)
+	}
+	if expected := []string{"id-a", "id-b", "id-c", "id-d", "id-e"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected results %v in the order of the operations, got %v", expected, ids)
+	}
+}
+
+func TestBulkResponseMatching(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ServiceProviderConfig":
+			fmt.Fprint(w, `{"bulk":{"supported":true}}`)
+		case "/Bulk":
+			// operations with bulkId are matched by it, others by position; the last operation was not processed
+			fmt.Fprint(w, `{"Operations":[
+				{"method":"DELETE","status":"204"},
+				{"method":"POST","bulkId":"y","location":"https://example.com/Users/2","status":"201"},
+				{"method":"POST","bulkId":"x","status":"409","response":{"detail":"duplicate"}}
+			]}`)
+		}
+	})
+
+	results, err := c.Bulk([]BulkOperation{
+		{Method: "DELETE", Path: "/Users/1"},
+		{Method: "POST", BulkID: "x", Path: "/Users"},
+		{Method: "POST", BulkID: "y", Path: "/Users"},
+		{Method: "DELETE", Path: "/Users/3"},
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %v", len(results))
+	}
+	if results[0].StatusCode != 204 || results[0].Err != nil {
+		t.Errorf("expected DELETE to succeed, got %v %v", results[0].StatusCode, results[0].Err)
+	}
+	if results[1].BulkID != "x" || results[1].StatusCode != 409 || results[1].Err == nil {
+		t.Errorf("expected operation x to fail with 409, got %+v", results[1])
+	}
+	if results[2].BulkID != "y" || results[2].ID != "2" {
+		t.Errorf("expected operation y to create 2, got %+v", results[2])
+	}
+}
+
+func TestBulkFallback(t *testing.T) {
+	var mu sync.Mutex
+	requests := []string{}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/ServiceProviderConfig":
+			fmt.Fprint(w, `{"bulk":{"supported":true}}`)
+		case r.URL.Path == "/Groups" && r.Method == "POST":
+			w.WriteHeader(201)
+			fmt.Fprint(w, `{"id":"g-1"}`)
+		case r.URL.Path == "/Groups/g-1" && r.Method == "PATCH":
+			w.WriteHeader(204)
+		default:
+			// the advertised /Bulk endpoint is missing
+			w.WriteHeader(404)
+		}
+	})
+
+	results, err := c.Bulk([]BulkOperation{
+		{Method: "POST", BulkID: "g", Path: "/Groups", Data: map[string]string{"displayName": "g"}},
+		{Method: "PATCH", Path: "/Groups/bulkId:g"},
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"GET /ServiceProviderConfig", "POST /Bulk", "POST /Groups", "PATCH /Groups/g-1"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+	if len(results) != 2 || results[0].ID != "g-1" || results[1].Err != nil {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestServiceProviderConfigRetriesFailures(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"bulk":{"supported":true}}`)
+	})
+
+	if _, err := c.ServiceProviderConfig(); err == nil {
+		t.Fatal("expected the first request to fail")
+	}
+
+	spc, err := c.ServiceProviderConfig()
+	if err != nil || !spc.Bulk.Supported {
+		t.Fatalf("expected the failure not to be kept, got %v", err)
+	}
+
+	c.ServiceProviderConfig()
+	if calls != 2 {
+		t.Errorf("expected the successful response to be kept, got %v requests", calls)
+	}
+}
@@ -7,10 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"golang.org/x/time/rate"
 )
 
@@ -24,11 +28,102 @@ type RLHttpClient struct {
 	RateLimiter *rate.Limiter
 }
 
+// Settings are configured once per provider and shared by the clients of all instances.
+type Settings struct {
+	// reads of objects written within ConsistencyTimeout wait for them to become visible
+	ConsistencyTimeout time.Duration
+
+	// refuse all mutating requests
+	ReadOnly bool
+
+	UserDefaults  UserDefaults
+	Normalization NormalizationRules
+	Policy        PolicyRules
+
+	// what happens when an object has been deleted outside of Terraform
+	DriftPolicy string
+
+	// keep personal data of users out of logs
+	SensitivePII bool
+
+	// records every mutating request, if configured
+	AuditLog *AuditLog
+
+	// deliver events of successful writes to their sinks in the background
+	EventQueues []*EventQueue
+
+	// mutating requests fail during freeze windows, unless an override reason is given
+	FreezeWindows        []FreezeWindow
+	FreezeOverrideReason string
+
+	Protected ProtectedPrincipals
+
+	// externalId prefix of objects created by this configuration, others are only changed if allowed
+	OwnershipPrefix     string
+	AllowUnownedChanges bool
+
+	// deletions are counted across all instances
+	DeletionLimits DeletionLimits
+	deletions      deletionCounter
+}
+
 type APIClient struct {
-	BaseURL    *url.URL
-	Token      string
-	httpClient *RLHttpClient
-	UserAgent  string
+	*Settings
+
+	BaseURL     *url.URL
+	TokenSource TokenSource
+	httpClient  *RLHttpClient
+	UserAgent   string
+
+	// clients of further instances by name, each with its own rate limiter
+	Instances map[string]*APIClient
+
+	// replays writes on a second endpoint, if configured
+	Mirror *Mirror
+
+	*clientState
+
+	// diagnostics of the current resource operation, see forOperation, e.g. warnings about rejected tokens
+	diags diagnosticsBuffer
+}
+
+// clientState is shared by a client and its copies for resource operations.
+type clientState struct {
+	writesMu sync.Mutex
+	writes   map[string]time.Time
+
+	// some warnings are only given once per client
+	warnedMu        sync.Mutex
+	deadTokens      map[int]bool
+	freezeOverrides map[string]bool
+
+	// diagnostics of background work, e.g. event delivery, reported by the next resource operation
+	background diagnosticsBuffer
+
+	// ServiceProviderConfig is only fetched once per client
+	spcMu sync.Mutex
+	spc   *ServiceProviderConfig
+}
+
+type diagnosticsBuffer struct {
+	mu    sync.Mutex
+	diags diag.Diagnostics
+}
+
+func (b *diagnosticsBuffer) add(d diag.Diagnostic) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.diags = append(b.diags, d)
+}
+
+func (b *diagnosticsBuffer) drain() diag.Diagnostics {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	diags := b.diags
+	b.diags = nil
+	return diags
 }
 
 func (c *RLHttpClient) Do(req *http.Request) (*http.Response, error) {
@@ -44,13 +139,13 @@ func (c *RLHttpClient) Do(req *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
-func NewClient(endpoint string, token string, UserAgent string) (*APIClient, error) {
+func NewClient(endpoint string, tokenSource TokenSource, UserAgent string) (*APIClient, error) {
 
-	if endpoint == "" || token == "" {
+	if endpoint == "" || tokenSource == nil {
 		return nil, fmt.Errorf("token and endpoint are required")
 	}
 
-	baseURL, err := url.Parse(endpoint)
+	baseURL, err := normalizeEndpoint(endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -67,17 +162,51 @@ func NewClient(endpoint string, token string, UserAgent string) (*APIClient, err
 	}
 
 	c := &APIClient{
-		httpClient: rlClient,
-		BaseURL:    baseURL,
-		Token:      token,
-		UserAgent:  UserAgent,
+		Settings: &Settings{
+			ConsistencyTimeout: time.Duration(DefaultConsistencyTimeout) * time.Second,
+		},
+		httpClient:  rlClient,
+		BaseURL:     baseURL,
+		TokenSource: tokenSource,
+		UserAgent:   UserAgent,
+		Instances:   map[string]*APIClient{},
+		clientState: &clientState{},
 	}
 
 	return c, nil
 }
 
-func (c *APIClient) newRequest(method, path string, filter string, body interface{}) (*http.Request, error) {
+// forOperation returns a copy of the client, its instances and mirror for a single resource operation,
+// which collects its own diagnostics. Everything else is shared with c.
+func (c *APIClient) forOperation() *APIClient {
+	op := &APIClient{
+		Settings:    c.Settings,
+		BaseURL:     c.BaseURL,
+		TokenSource: c.TokenSource,
+		httpClient:  c.httpClient,
+		UserAgent:   c.UserAgent,
+		Instances:   make(map[string]*APIClient, len(c.Instances)),
+		clientState: c.clientState,
+	}
+
+	for name, instance := range c.Instances {
+		op.Instances[name] = instance.forOperation()
+	}
+
+	if c.Mirror != nil {
+		op.Mirror = &Mirror{client: c.Mirror.client.forOperation(), mode: c.Mirror.mode, mapping: c.Mirror.mapping}
+	}
+
+	return op
+}
+
+func (c *APIClient) newRequest(method, path string, filter string, body interface{}) (*http.Request, *Token, error) {
 	rel := &url.URL{Path: path}
+	// path may carry additional query parameters, e.g. attributes for projection
+	if i := strings.Index(path, "?"); i >= 0 {
+		rel.Path = path[:i]
+		rel.RawQuery = path[i+1:]
+	}
 	u := c.BaseURL.ResolveReference(rel)
 
 	if filter != "" {
@@ -91,23 +220,28 @@ func (c *APIClient) newRequest(method, path string, filter string, body interfac
 		buf = new(bytes.Buffer)
 		err := json.NewEncoder(buf).Encode(body)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	req, err := http.NewRequest(method, u.String(), buf)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", c.Token))
+	token, err := c.TokenSource.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token.Value))
 	req.Header.Set("User-Agent", c.UserAgent)
 	req.Header.Set("Accept", "application/json")
 
-	return req, nil
+	return req, token, nil
 }
 
 func (c *APIClient) do(req *http.Request, v interface{}) (*http.Response, error) {
@@ -126,7 +260,7 @@ func (c *APIClient) do(req *http.Request, v interface{}) (*http.Response, error)
 		return resp, errors.New("409 conflict, resource already exists")
 	case resp.StatusCode == 429:
 		return resp, errors.New("429 ThrottlingException")
-	case resp.StatusCode == 200 || resp.StatusCode == 201:
+	case (resp.StatusCode == 200 || resp.StatusCode == 201) && v != nil:
 		err = json.NewDecoder(resp.Body).Decode(v)
 		return resp, err
 	case resp.StatusCode == 204:
@@ -134,20 +268,175 @@ func (c *APIClient) do(req *http.Request, v interface{}) (*http.Response, error)
 	case resp.StatusCode <= 299 && resp.StatusCode >= 200:
 		return resp, err
 	default:
-		return nil, fmt.Errorf("unexpected HTTP status code: %v", resp.StatusCode)
+		return resp, fmt.Errorf("unexpected HTTP status code: %v", resp.StatusCode)
 	}
 }
 
 func (c *APIClient) doRequest(method, path string, filter string, body interface{}, v interface{}) (*http.Response, error) {
-	req, err := c.newRequest(method, path, filter, body)
+	if c.ReadOnly && isMutatingMethod(method) {
+		return nil, fmt.Errorf("read_only is enabled, refusing to send %v %v", method, path)
+	}
+	if isMutatingMethod(method) {
+		if err := c.refuseFrozen(fmt.Sprintf("send %v %v", method, path)); err != nil {
+			return nil, err
+		}
+	}
+
+	// the operations of bulk requests pass the same checks as single requests
+	if method == "POST" && strings.TrimPrefix(path, "/") == "Bulk" {
+		if err := c.refuseBulkRequest(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, token, err := c.newRequest(method, path, filter, body)
 	if err != nil {
 		return nil, err
 	}
 
+	// deleted objects can't be read afterwards
+	existingKey := c.existingNaturalKey(method, path, body)
+
+	var mirrored *mirrorRequest
+	if c.Mirror != nil && isMutatingMethod(method) {
+		mirrored = c.prepareReplication(method, path, body)
+	}
+
+	c.logRequest(method, path, body)
+
+	start := time.Now()
 	resp, err := c.do(req, v)
+
+	// retry once with the next token, if the token has been rejected
+	if resp != nil && resp.StatusCode == 401 {
+		if fs, ok := c.TokenSource.(*multiTokenSource); ok {
+			if dead, next, ok := fs.Failover(token); ok {
+				c.warnDeadToken(dead, next)
+
+				req, _, err = c.newRequest(method, path, filter, body)
+				if err != nil {
+					return nil, err
+				}
+				resp, err = c.do(req, v)
+			}
+		}
+	}
+
+	c.audit(method, path, body, existingKey, v, resp, err, time.Since(start))
+
+	if err == nil && isMutatingMethod(method) {
+		c.publish(method, path, body, v)
+
+		if mirrored != nil {
+			c.replicate(mirrored, v)
+		}
+	}
+
 	return resp, err
 }
 
+// logRequest logs the request at debug level, including the body of mutating requests.
+func (c *APIClient) logRequest(method, path string, body interface{}) {
+	if !isMutatingMethod(method) || body == nil {
+		log.Printf("[DEBUG] SCIM request %v %v", method, path)
+		return
+	}
+
+	// personal data never reaches the logs, regardless of SensitivePIIEnv
+	encoded, _ := json.Marshal(redactPII(path, body))
+	log.Printf("[DEBUG] SCIM request %v %v %s", method, path, encoded)
+}
+
+// warnDeadToken adds a warning about a rejected token, once per token.
+func (c *APIClient) warnDeadToken(dead int, next int) {
+	c.warnedMu.Lock()
+	defer c.warnedMu.Unlock()
+
+	if c.deadTokens == nil {
+		c.deadTokens = map[int]bool{}
+	}
+	if c.deadTokens[dead] {
+		return
+	}
+	c.deadTokens[dead] = true
+
+	c.diags.add(diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  "Authentication token rejected",
+		Detail:   fmt.Sprintf("The token at index %v of tokens was rejected with 401 unauthorized, using the token at index %v instead. Please remove or rotate the rejected token.", dead, next),
+	})
+}
+
+// drainDiagnostics returns and clears the diagnostics collected by the client and the clients of
+// all instances since the last call, including those of background work.
+func (c *APIClient) drainDiagnostics() diag.Diagnostics {
+	diags := append(c.diags.drain(), c.background.drain()...)
+
+	for name, instance := range c.Instances {
+		for _, d := range instance.drainDiagnostics() {
+			d.Detail = fmt.Sprintf("Instance %q: %v", name, d.Detail)
+			diags = append(diags, d)
+		}
+	}
+
+	if c.Mirror != nil {
+		for _, d := range c.Mirror.client.drainDiagnostics() {
+			d.Detail = fmt.Sprintf("Mirror: %v", d.Detail)
+			diags = append(diags, d)
+		}
+	}
+
+	return diags
+}
+
+// Instance returns the client of the named instance, or c itself for an empty name.
+func (c *APIClient) Instance(name string) (*APIClient, error) {
+	if name == "" {
+		return c, nil
+	}
+
+	instance, ok := c.Instances[name]
+	if !ok {
+		return nil, fmt.Errorf("instance %q is not configured in the provider", name)
+	}
+
+	return instance, nil
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+func isNotFound(resp *http.Response, err error) bool {
+	return err != nil && resp != nil && resp.StatusCode == 404
+}
+
+// isAmbiguousWriteError reports whether a failed write might have been applied by the server anyway,
+// e.g. because the request timed out or an earlier, timed out request already created the resource.
+// Requests refused before sending, e.g. in read-only mode or during a freeze window, are not ambiguous.
+func isAmbiguousWriteError(resp *http.Response, err error) bool {
+	if resp != nil {
+		return resp.StatusCode == 409 || resp.StatusCode >= 500
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// warnAdoption adds a warning about an existing user or group adopted after an ambiguous create, so taking
+// over an object created elsewhere doesn't go unnoticed.
+func (c *APIClient) warnAdoption(kind string, id string, name string, reason error) {
+	c.diags.add(diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  fmt.Sprintf("Existing %v adopted", kind),
+		Detail:   fmt.Sprintf("Creating the %v %q failed (%v), the existing %v %q with the same name has been adopted instead.", kind, name, reason, kind, id),
+	})
+}
+
 func (c *APIClient) ListUsers() (*[]User, *http.Response, error) {
 	var userLR UserListResponse
 	resp, err := c.doRequest("GET", "Users", "", nil, &userLR)
@@ -155,30 +444,94 @@ func (c *APIClient) ListUsers() (*[]User, *http.Response, error) {
 }
 
 func (c *APIClient) CreateUser(user *User) (*User, *http.Response, error) {
+	user.ExternalID = c.stampExternalID(user.ExternalID)
+
 	var userResponse User
 	resp, err := c.doRequest("POST", "Users", "", user, &userResponse)
+
+	// the user might exist already, so we adopt it by looking it up by its natural key
+	if err != nil && isAmbiguousWriteError(resp, err) {
+		if existing, findResp, findErr := c.findUserByNaturalKey(user); findErr == nil {
+			if err := c.refuseAdoption("user", existing.ID, existing.UserName, existing.ExternalID); err != nil {
+				return &userResponse, resp, err
+			}
+			c.warnAdoption("user", existing.ID, existing.UserName, err)
+			return existing, findResp, nil
+		}
+	}
+
+	if err == nil {
+		c.markWritten(fmt.Sprintf("Users/%v", userResponse.ID))
+	}
+
 	return &userResponse, resp, err
 }
 
+func (c *APIClient) findUserByNaturalKey(user *User) (*User, *http.Response, error) {
+	if c.unstampExternalID(user.ExternalID) != "" {
+		return c.FindUserByExternalID(user.ExternalID)
+	}
+	return c.FindUserByUsername(user.UserName)
+}
+
 func (c *APIClient) PatchUser(opmsg *OperationMessage, id string) (*User, *http.Response, error) {
 	var userResponse User
+	if err := c.refuseProtectedPatch("Users", opmsg, id); err != nil {
+		return &userResponse, nil, err
+	}
+	if err := c.refuseUnowned("update it", "Users", id); err != nil {
+		return &userResponse, nil, err
+	}
 	resp, err := c.doRequest("PATCH", fmt.Sprintf("Users/%v", id), "", opmsg, &userResponse)
 	return &userResponse, resp, err
 }
 
 func (c *APIClient) PutUser(user *User, id string) (*User, *http.Response, error) {
 	var userResponse User
+	if err := c.refuseProtectedUser("deactivate it", id, user); err != nil {
+		return &userResponse, nil, err
+	}
+	if err := c.refuseUnowned("update it", "Users", id); err != nil {
+		return &userResponse, nil, err
+	}
 	resp, err := c.doRequest("PUT", fmt.Sprintf("Users/%v", id), "", user, &userResponse)
 	return &userResponse, resp, err
 }
 
 func (c *APIClient) DeleteUser(id string) (*http.Response, error) {
-	return c.doRequest("DELETE", fmt.Sprintf("Users/%v", id), "", nil, nil)
+	if err := c.refuseProtectedUser("delete it", id, nil); err != nil {
+		return nil, err
+	}
+	if err := c.refuseUnowned("delete it", "Users", id); err != nil {
+		return nil, err
+	}
+	if err := c.countDeletion("user", id); err != nil {
+		return nil, err
+	}
+
+	c.forgetWritten(fmt.Sprintf("Users/%v", id))
+
+	resp, err := c.doRequest("DELETE", fmt.Sprintf("Users/%v", id), "", nil, nil)
+
+	// the user is gone already
+	if isNotFound(resp, err) {
+		return resp, nil
+	}
+
+	return resp, err
 }
 
 func (c *APIClient) ReadUser(id string) (*User, *http.Response, error) {
 	var userResponse User
-	resp, err := c.doRequest("GET", fmt.Sprintf("Users/%v", id), "", nil, &userResponse)
+	var resp *http.Response
+	var err error
+
+	// a 404 right after creation means the user is not visible yet
+	c.waitForConsistency(fmt.Sprintf("Users/%v", id), func() bool {
+		resp, err = c.doRequest("GET", fmt.Sprintf("Users/%v", id), "", nil, &userResponse)
+		return !isNotFound(resp, err)
+	})
+
 	return &userResponse, resp, err
 }
 
@@ -198,6 +551,22 @@ func (c *APIClient) FindUserByUsername(username string) (*User, *http.Response,
 	return &userLR.Resources[0], resp, nil
 }
 
+func (c *APIClient) FindUserByExternalID(externalID string) (*User, *http.Response, error) {
+	filter := fmt.Sprintf("externalId eq \"%v\"", externalID)
+
+	var userLR UserListResponse
+	resp, err := c.doRequest("GET", "Users", filter, nil, &userLR)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if userLR.TotalResults != 1 || len(userLR.Resources) != 1 {
+		return nil, resp, fmt.Errorf("user with externalId \"%v\" not found", externalID)
+	}
+
+	return &userLR.Resources[0], resp, nil
+}
+
 func (c *APIClient) FindGroupByDisplayname(displayname string) (*Group, *http.Response, error) {
 	filter := fmt.Sprintf("displayName eq \"%v\"", displayname)
 
@@ -214,33 +583,115 @@ func (c *APIClient) FindGroupByDisplayname(displayname string) (*Group, *http.Re
 	return &groupLR.Resources[0], resp, nil
 }
 
+func (c *APIClient) FindGroupByExternalID(externalID string) (*Group, *http.Response, error) {
+	filter := fmt.Sprintf("externalId eq \"%v\"", externalID)
+
+	var groupLR GroupListResponse
+	resp, err := c.doRequest("GET", "Groups", filter, nil, &groupLR)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if groupLR.TotalResults != 1 || len(groupLR.Resources) != 1 {
+		return nil, resp, fmt.Errorf("group with externalId \"%v\" not found", externalID)
+	}
+
+	return &groupLR.Resources[0], resp, nil
+}
+
 func (c *APIClient) CreateGroup(group *Group) (*Group, *http.Response, error) {
+	group.ExternalID = c.stampExternalID(group.ExternalID)
+
 	var groupResponse Group
 	resp, err := c.doRequest("POST", "Groups", "", group, &groupResponse)
+
+	// the group might exist already, so we adopt it by looking it up by its natural key
+	if err != nil && isAmbiguousWriteError(resp, err) {
+		if existing, findResp, findErr := c.findGroupByNaturalKey(group); findErr == nil {
+			if err := c.refuseAdoption("group", existing.ID, existing.DisplayName, existing.ExternalID); err != nil {
+				return &groupResponse, resp, err
+			}
+			c.warnAdoption("group", existing.ID, existing.DisplayName, err)
+			return existing, findResp, nil
+		}
+	}
+
+	if err == nil {
+		c.markWritten(fmt.Sprintf("Groups/%v", groupResponse.ID))
+	}
+
 	return &groupResponse, resp, err
 }
 
+func (c *APIClient) findGroupByNaturalKey(group *Group) (*Group, *http.Response, error) {
+	if c.unstampExternalID(group.ExternalID) != "" {
+		return c.FindGroupByExternalID(group.ExternalID)
+	}
+	return c.FindGroupByDisplayname(group.DisplayName)
+}
+
 func (c *APIClient) ReadGroup(id string) (*Group, *http.Response, error) {
 	var groupResponse Group
-	resp, err := c.doRequest("GET", fmt.Sprintf("Groups/%v", id), "", nil, &groupResponse)
+	var resp *http.Response
+	var err error
+
+	// a 404 right after creation means the group is not visible yet
+	c.waitForConsistency(fmt.Sprintf("Groups/%v", id), func() bool {
+		resp, err = c.doRequest("GET", fmt.Sprintf("Groups/%v", id), "", nil, &groupResponse)
+		return !isNotFound(resp, err)
+	})
+
 	return &groupResponse, resp, err
 }
 
 func (c *APIClient) PatchGroup(opmsg *OperationMessage, id string) (*Group, *http.Response, error) {
 	var groupResponse Group
+	if err := c.refuseProtectedPatch("Groups", opmsg, id); err != nil {
+		return &groupResponse, nil, err
+	}
+	if err := c.refuseUnowned("update it", "Groups", id); err != nil {
+		return &groupResponse, nil, err
+	}
 	resp, err := c.doRequest("PATCH", fmt.Sprintf("Groups/%v", id), "", opmsg, &groupResponse)
 	return &groupResponse, resp, err
 }
 
 func (c *APIClient) DeleteGroup(id string) (*http.Response, error) {
-	return c.doRequest("DELETE", fmt.Sprintf("Groups/%v", id), "", nil, nil)
+	if err := c.refuseProtectedGroup("delete it", id); err != nil {
+		return nil, err
+	}
+	if err := c.refuseUnowned("delete it", "Groups", id); err != nil {
+		return nil, err
+	}
+	if err := c.countDeletion("group", id); err != nil {
+		return nil, err
+	}
+
+	c.forgetWritten(fmt.Sprintf("Groups/%v", id))
+
+	resp, err := c.doRequest("DELETE", fmt.Sprintf("Groups/%v", id), "", nil, nil)
+
+	// the group is gone already
+	if isNotFound(resp, err) {
+		return resp, nil
+	}
+
+	return resp, err
 }
 
 func (c *APIClient) TestGroupMember(group_id string, user_id string) (bool, *http.Response, error) {
 	filter := fmt.Sprintf("id eq \"%v\" and members eq \"%v\"", group_id, user_id)
 
 	var groupLR GroupListResponse
-	resp, err := c.doRequest("GET", "Groups", filter, nil, &groupLR)
+	var resp *http.Response
+	var err error
+
+	// a missing membership right after adding it means it is not visible yet
+	c.waitForConsistency(groupMemberKey(group_id, user_id), func() bool {
+		groupLR = GroupListResponse{}
+		resp, err = c.doRequest("GET", "Groups", filter, nil, &groupLR)
+		return err != nil || (groupLR.TotalResults == 1 && len(groupLR.Resources) == 1)
+	})
 	if err != nil {
 		return false, resp, err
 	}
@@ -248,7 +699,14 @@ func (c *APIClient) TestGroupMember(group_id string, user_id string) (bool, *htt
 	return !(groupLR.TotalResults != 1 || len(groupLR.Resources) != 1), resp, nil
 }
 
+func groupMemberKey(group_id string, user_id string) string {
+	return fmt.Sprintf("Groups/%v/members/%v", group_id, user_id)
+}
+
 func (c *APIClient) AddGroupMember(group_id string, user_id string) (*http.Response, error) {
+	if err := c.refuseUnowned("add members to it", "Groups", group_id); err != nil {
+		return nil, err
+	}
 
 	opmsg := OperationMessage{
 		Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
@@ -263,10 +721,32 @@ func (c *APIClient) AddGroupMember(group_id string, user_id string) (*http.Respo
 		},
 	}
 
-	return c.doRequest("PATCH", fmt.Sprintf("Groups/%v", group_id), "", opmsg, nil)
+	resp, err := c.doRequest("PATCH", fmt.Sprintf("Groups/%v", group_id), "", opmsg, nil)
+
+	// the user might be a member already
+	if err != nil && isAmbiguousWriteError(resp, err) {
+		if is_member, testResp, testErr := c.TestGroupMember(group_id, user_id); testErr == nil && is_member {
+			return testResp, nil
+		}
+	}
+
+	if err == nil {
+		c.markWritten(groupMemberKey(group_id, user_id))
+	}
+
+	return resp, err
 }
 
 func (c *APIClient) RemoveGroupMember(group_id string, user_id string) (*http.Response, error) {
+	if err := c.refuseProtectedMembership(group_id, user_id); err != nil {
+		return nil, err
+	}
+	if err := c.refuseUnowned("remove members from it", "Groups", group_id); err != nil {
+		return nil, err
+	}
+	if err := c.countDeletion("group membership", fmt.Sprintf("of user %v in group %v", user_id, group_id)); err != nil {
+		return nil, err
+	}
 
 	opmsg := OperationMessage{
 		Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
@@ -281,5 +761,91 @@ func (c *APIClient) RemoveGroupMember(group_id string, user_id string) (*http.Re
 		},
 	}
 
-	return c.doRequest("PATCH", fmt.Sprintf("Groups/%v", group_id), "", opmsg, nil)
+	c.forgetWritten(groupMemberKey(group_id, user_id))
+
+	resp, err := c.doRequest("PATCH", fmt.Sprintf("Groups/%v", group_id), "", opmsg, nil)
+
+	// the group is gone already, or the user is no member anymore
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return resp, nil
+		}
+		if is_member, testResp, testErr := c.TestGroupMember(group_id, user_id); testErr == nil && !is_member {
+			return testResp, nil
+		}
+	}
+
+	return resp, err
+}
+
+func (c *APIClient) CreateResource(endpoint string, resource map[string]interface{}) (map[string]interface{}, *http.Response, error) {
+	if isOwnedEndpoint(endpoint) && c.OwnershipPrefix != "" {
+		externalID, _ := resource["externalId"].(string)
+		resource["externalId"] = c.stampExternalID(externalID)
+	}
+
+	var resourceResponse map[string]interface{}
+	resp, err := c.doRequest("POST", endpoint, "", resource, &resourceResponse)
+
+	if id, ok := resourceResponse["id"].(string); err == nil && ok {
+		c.markWritten(fmt.Sprintf("%v/%v", endpoint, id))
+	}
+
+	return resourceResponse, resp, err
+}
+
+func (c *APIClient) ReadResource(endpoint string, id string) (map[string]interface{}, *http.Response, error) {
+	var resourceResponse map[string]interface{}
+	var resp *http.Response
+	var err error
+
+	// a 404 right after creation means the resource is not visible yet
+	c.waitForConsistency(fmt.Sprintf("%v/%v", endpoint, id), func() bool {
+		resp, err = c.doRequest("GET", fmt.Sprintf("%v/%v", endpoint, id), "", nil, &resourceResponse)
+		return !isNotFound(resp, err)
+	})
+
+	return resourceResponse, resp, err
+}
+
+func (c *APIClient) PatchResource(endpoint string, opmsg *OperationMessage, id string) (map[string]interface{}, *http.Response, error) {
+	var resourceResponse map[string]interface{}
+	if err := c.refuseProtectedPatch(endpoint, opmsg, id); err != nil {
+		return resourceResponse, nil, err
+	}
+	if err := c.refuseUnowned("update it", endpoint, id); err != nil {
+		return resourceResponse, nil, err
+	}
+	resp, err := c.doRequest("PATCH", fmt.Sprintf("%v/%v", endpoint, id), "", opmsg, &resourceResponse)
+	return resourceResponse, resp, err
+}
+
+func (c *APIClient) DeleteResource(endpoint string, id string) (*http.Response, error) {
+	switch endpoint {
+	case "Users":
+		if err := c.refuseProtectedUser("delete it", id, nil); err != nil {
+			return nil, err
+		}
+	case "Groups":
+		if err := c.refuseProtectedGroup("delete it", id); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.refuseUnowned("delete it", endpoint, id); err != nil {
+		return nil, err
+	}
+	if err := c.countDeletion(deletionKind(endpoint), id); err != nil {
+		return nil, err
+	}
+
+	c.forgetWritten(fmt.Sprintf("%v/%v", endpoint, id))
+
+	resp, err := c.doRequest("DELETE", fmt.Sprintf("%v/%v", endpoint, id), "", nil, nil)
+
+	// the resource is gone already
+	if isNotFound(resp, err) {
+		return resp, nil
+	}
+
+	return resp, err
 }
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+func TestIsAmbiguousWriteError(t *testing.T) {
+	cases := []struct {
+		name     string
+		resp     *http.Response
+		err      error
+		expected bool
+	}{
+		{"conflict", &http.Response{StatusCode: 409}, errors.New("409 conflict"), true},
+		{"server error", &http.Response{StatusCode: 502}, errors.New("502"), true},
+		{"bad request", &http.Response{StatusCode: 400}, errors.New("400"), false},
+		{"timeout", nil, &url.Error{Op: "Post", URL: "https://example.com", Err: errors.New("timeout")}, true},
+		{"refused before sending", nil, errors.New("provider is read-only"), false},
+	}
+
+	for _, tc := range cases {
+		if actual := isAmbiguousWriteError(tc.resp, tc.err); actual != tc.expected {
+			t.Errorf("%v: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestRefusedWritesAreNotAdopted(t *testing.T) {
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		// a lookup by natural key would find an existing user or group
+		w.Write([]byte(`{"totalResults":1,"Resources":[{"id":"existing","userName":"jdoe","displayName":"g"}]}`))
+	})
+	c.ReadOnly = true
+
+	if _, _, err := c.CreateUser(&User{UserName: "jdoe"}); err == nil {
+		t.Error("expected CreateUser to fail in read-only mode")
+	}
+	if _, _, err := c.CreateGroup(&Group{DisplayName: "g"}); err == nil {
+		t.Error("expected CreateGroup to fail in read-only mode")
+	}
+	if _, err := c.AddGroupMember("existing", "existing"); err == nil {
+		t.Error("expected AddGroupMember to fail in read-only mode")
+	}
+
+	if requests != 0 {
+		t.Errorf("expected no requests, got %v", requests)
+	}
+}
+
+func TestAdoptionIsWarned(t *testing.T) {
+	c := newTestClient(t, conflictServer("idp-1234"))
+
+	user, _, err := c.CreateUser(&User{UserName: "jdoe"})
+	if err != nil || user.ID != "existing" {
+		t.Fatalf("expected the existing user to be adopted, got %v", err)
+	}
+
+	diags := c.drainDiagnostics()
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || !strings.Contains(diags[0].Detail, `user "existing"`) {
+		t.Errorf("expected a warning naming the adopted user, got %v", diags)
+	}
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"time"
+)
+
+const (
+	// Wait up to 30 seconds for writes to become visible
+	DefaultConsistencyTimeout int = 30
+
+	consistencyInitialBackoff = 250 * time.Millisecond
+	consistencyMaxBackoff     = 5 * time.Second
+)
+
+// markWritten records that the object identified by key has just been written,
+// so reads within ConsistencyTimeout wait for it to become visible.
+func (c *APIClient) markWritten(key string) {
+	if c.ConsistencyTimeout <= 0 {
+		return
+	}
+
+	c.writesMu.Lock()
+	defer c.writesMu.Unlock()
+
+	if c.writes == nil {
+		c.writes = map[string]time.Time{}
+	}
+	c.writes[key] = time.Now()
+}
+
+// forgetWritten stops waiting for the object identified by key, e.g. because it has been deleted.
+func (c *APIClient) forgetWritten(key string) {
+	c.writesMu.Lock()
+	defer c.writesMu.Unlock()
+
+	delete(c.writes, key)
+}
+
+func (c *APIClient) consistencyDeadline(key string) (time.Time, bool) {
+	c.writesMu.Lock()
+	defer c.writesMu.Unlock()
+
+	written, ok := c.writes[key]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	deadline := written.Add(c.ConsistencyTimeout)
+	if time.Now().After(deadline) {
+		delete(c.writes, key)
+		return time.Time{}, false
+	}
+
+	return deadline, true
+}
+
+// waitForConsistency calls visible until it returns true. If the object identified by key has
+// not been written recently, visible is called only once. Otherwise it is polled with exponential
+// backoff until the write is older than ConsistencyTimeout.
+func (c *APIClient) waitForConsistency(key string, visible func() bool) {
+	backoff := consistencyInitialBackoff
+
+	for !visible() {
+		deadline, ok := c.consistencyDeadline(key)
+		if !ok {
+			return
+		}
+
+		wait := backoff
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		time.Sleep(wait)
+
+		backoff *= 2
+		if backoff > consistencyMaxBackoff {
+			backoff = consistencyMaxBackoff
+		}
+	}
+}
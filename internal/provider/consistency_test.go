@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// consistencyServer creates the user 1, which only becomes visible after invisibleReads reads.
+// Deleted users are never visible.
+type consistencyServer struct {
+	mu             sync.Mutex
+	invisibleReads int
+	reads          int
+}
+
+func (s *consistencyServer) readCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reads
+}
+
+func (s *consistencyServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case "POST":
+		w.WriteHeader(201)
+		fmt.Fprint(w, `{"id":"1","userName":"jdoe"}`)
+	case "DELETE":
+		s.invisibleReads = -1
+		w.WriteHeader(204)
+	case "GET":
+		s.reads++
+		if s.invisibleReads < 0 || s.reads <= s.invisibleReads {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprint(w, `{"id":"1","userName":"jdoe"}`)
+	}
+}
+
+func TestConsistencyRetriesWithinTimeout(t *testing.T) {
+	server := &consistencyServer{invisibleReads: 2}
+	c := newTestClient(t, server.handle)
+
+	if _, _, err := c.CreateUser(&User{UserName: "jdoe"}); err != nil {
+		t.Fatal(err)
+	}
+
+	user, _, err := c.ReadUser("1")
+	if err != nil || user.UserName != "jdoe" {
+		t.Fatalf("expected the user to become visible, got %v", err)
+	}
+	if server.readCount() != 3 {
+		t.Errorf("expected 3 reads, got %v", server.readCount())
+	}
+}
+
+func TestConsistencyGivesUpAfterTimeout(t *testing.T) {
+	server := &consistencyServer{invisibleReads: 1000}
+	c := newTestClient(t, server.handle)
+	c.ConsistencyTimeout = 500 * time.Millisecond
+
+	if _, _, err := c.CreateUser(&User{UserName: "jdoe"}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, resp, err := c.ReadUser("1")
+	if !isNotFound(resp, err) {
+		t.Fatalf("expected the user not to be found, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the wait to end with the timeout, took %v", elapsed)
+	}
+	if server.readCount() < 2 {
+		t.Errorf("expected the read to be retried, got %v reads", server.readCount())
+	}
+
+	// later reads are not retried
+	before := server.readCount()
+	c.ReadUser("1")
+	if server.readCount() != before+1 {
+		t.Errorf("expected a single read after the timeout, got %v", server.readCount()-before)
+	}
+}
+
+func TestConsistencyStopsWaitingAfterDelete(t *testing.T) {
+	server := &consistencyServer{invisibleReads: 0}
+	c := newTestClient(t, server.handle)
+
+	if _, _, err := c.CreateUser(&User{UserName: "jdoe"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.DeleteUser("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, resp, err := c.ReadUser("1"); !isNotFound(resp, err) {
+		t.Fatalf("expected the user not to be found, got %v", err)
+	}
+	if server.readCount() != 1 {
+		t.Errorf("expected a single read of the deleted user, got %v", server.readCount())
+	}
+}
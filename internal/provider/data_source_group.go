@@ -27,7 +27,10 @@ func dataSourceGroup() *schema.Resource {
 
 func dataSourceGroupRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	diags := diag.Diagnostics{}
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
 	group, _, err := client.FindGroupByDisplayname(d.Get("display_name").(string))
 
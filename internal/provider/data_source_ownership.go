@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceOwnership() *schema.Resource {
+	return &schema.Resource{
+		Description: "Lists the users and groups which are managed by this configuration, recognized by the ownership prefix of their externalId, and those which are not.",
+		ReadContext: dataSourceOwnershipRead,
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"ownership_prefix": {
+				Description: "Prefix of the externalId of managed objects. Defaults to `ownership_prefix` of the provider.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"managed_users": {
+				Description: "User names of managed users by ID.",
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"unmanaged_users": {
+				Description: "User names of unmanaged users by ID.",
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"managed_groups": {
+				Description: "Display names of managed groups by ID.",
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"unmanaged_groups": {
+				Description: "Display names of unmanaged groups by ID.",
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func dataSourceOwnershipRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	diags := diag.Diagnostics{}
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	prefix := d.Get("ownership_prefix").(string)
+	if prefix == "" {
+		prefix = client.OwnershipPrefix
+	}
+	if prefix == "" {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Missing ownership prefix",
+			Detail:   "Either ownership_prefix of the data source or of the provider is required.",
+		})
+		return diags
+	}
+
+	users, err := client.ListAllUsers()
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to list Users",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	groups, err := client.ListAllGroups()
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to list Groups",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	managedUsers, unmanagedUsers := map[string]string{}, map[string]string{}
+	for _, user := range users {
+		if strings.HasPrefix(user.ExternalID, prefix) {
+			managedUsers[user.ID] = user.UserName
+		} else {
+			unmanagedUsers[user.ID] = user.UserName
+		}
+	}
+
+	managedGroups, unmanagedGroups := map[string]string{}, map[string]string{}
+	for _, group := range groups {
+		if strings.HasPrefix(group.ExternalID, prefix) {
+			managedGroups[group.ID] = group.DisplayName
+		} else {
+			unmanagedGroups[group.ID] = group.DisplayName
+		}
+	}
+
+	d.SetId(prefix)
+	d.Set("managed_users", managedUsers)
+	d.Set("unmanaged_users", unmanagedUsers)
+	d.Set("managed_groups", managedGroups)
+	d.Set("unmanaged_groups", unmanagedGroups)
+
+	return diags
+}
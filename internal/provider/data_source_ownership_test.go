@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceOwnership(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceOwnership,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.aws-sso-scim_ownership.foo", "id", "terraform-test:"),
+					resource.TestCheckResourceAttr("data.aws-sso-scim_ownership.foo", "managed_groups.%", "1"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceOwnership = `
+resource "aws-sso-scim_group" "foo" {
+  display_name = "terraform-test-ownership-group"
+  external_id  = "terraform-test:ownership-group"
+}
+
+data "aws-sso-scim_ownership" "foo" {
+  ownership_prefix = "terraform-test:"
+
+  depends_on = [aws-sso-scim_group.foo]
+}
+`
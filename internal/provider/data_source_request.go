@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceRequest() *schema.Resource {
+	return &schema.Resource{
+		Description: "Performs an arbitrary GET request against the SCIM endpoint and returns the JSON response.",
+		ReadContext: dataSourceRequestRead,
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"path": {
+				Description: "Path relative to the SCIM endpoint, e.g. `Users` or `Groups/<id>`.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"filter": {
+				Description: "SCIM filter expression, e.g. `userName eq \"john.doe@example.com\"`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			"attributes": {
+				Description: "Only return the given attributes.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"excluded_attributes": {
+				Description: "Do not return the given attributes.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"response": {
+				Description: "JSON encoded response of the SCIM endpoint.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceRequestRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	diags := diag.Diagnostics{}
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	path := strings.TrimPrefix(d.Get("path").(string), "/")
+	filter := d.Get("filter").(string)
+
+	query := url.Values{}
+	if attributes := expandStringList(d.Get("attributes").([]interface{})); len(attributes) > 0 {
+		query.Set("attributes", strings.Join(attributes, ","))
+	}
+	if excluded := expandStringList(d.Get("excluded_attributes").([]interface{})); len(excluded) > 0 {
+		query.Set("excludedAttributes", strings.Join(excluded, ","))
+	}
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+
+	var response json.RawMessage
+	_, err = client.doRequest("GET", path, filter, nil, &response)
+
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to perform request",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	id := path
+	if filter != "" {
+		id = id + " " + filter
+	}
+
+	d.SetId(id)
+	d.Set("response", string(response))
+
+	return diags
+}
+
+func expandStringList(list []interface{}) []string {
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceRequest(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceRequest,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.aws-sso-scim_request.foo", "id"),
+					resource.TestCheckResourceAttrSet("data.aws-sso-scim_request.foo", "response"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceRequest = `
+data "aws-sso-scim_request" "foo" {
+  path       = "Users"
+  filter     = "userName eq \"terraform-test-permanent-user\""
+  attributes = ["userName", "displayName"]
+}
+`
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Setting this environment variable to true disables max_deletions_per_run
+const DeletionLimitOverrideEnv = "AWS_SSO_SCIM_ALLOW_MASS_DELETION"
+
+// DeletionLimits are the maximum number of deletions per run, zero means unlimited.
+type DeletionLimits struct {
+	Users        int
+	Groups       int
+	GroupMembers int
+}
+
+// deletionCounter counts the deletions of all clients of a provider during a run.
+type deletionCounter struct {
+	mu           sync.Mutex
+	users        int
+	groups       int
+	groupMembers int
+}
+
+// countDeletion counts a deletion of kind ("user", "group" or "group membership") and
+// returns an error instead if the limit for kind has been reached already.
+func (c *APIClient) countDeletion(kind string, id string) error {
+	c.deletions.mu.Lock()
+	defer c.deletions.mu.Unlock()
+
+	var count *int
+	var limit int
+	switch kind {
+	case "user":
+		count, limit = &c.deletions.users, c.DeletionLimits.Users
+	case "group":
+		count, limit = &c.deletions.groups, c.DeletionLimits.Groups
+	case "group membership":
+		count, limit = &c.deletions.groupMembers, c.DeletionLimits.GroupMembers
+	default:
+		return nil
+	}
+
+	if limit > 0 && *count >= limit {
+		return fmt.Errorf("max_deletions_per_run allows %v %v deletions per run and all of them have been used, refusing to delete %v %v. Set %v=true to override", limit, kind, kind, id, DeletionLimitOverrideEnv)
+	}
+
+	*count++
+
+	return nil
+}
+
+// deletionKind returns the kind of deletion counted for objects of the SCIM endpoint.
+func deletionKind(endpoint string) string {
+	switch endpoint {
+	case "Users":
+		return "user"
+	case "Groups":
+		return "group"
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountDeletion(t *testing.T) {
+	c := &APIClient{Settings: &Settings{
+		DeletionLimits: DeletionLimits{Users: 2, Groups: 1},
+	}}
+
+	cases := []struct {
+		kind        string
+		expectError bool
+	}{
+		{"user", false},
+		{"user", false},
+		{"user", true},
+		{"group", false},
+		{"group", true},
+		// zero means unlimited
+		{"group membership", false},
+		{"group membership", false},
+		{"group membership", false},
+		// other kinds are not counted
+		{"", false},
+	}
+
+	for i, tc := range cases {
+		err := c.countDeletion(tc.kind, "1234")
+		if tc.expectError && (err == nil || !strings.Contains(err.Error(), DeletionLimitOverrideEnv)) {
+			t.Errorf("%v: expected deletion of %v to be refused, got %v", i, tc.kind, err)
+		}
+		if !tc.expectError && err != nil {
+			t.Errorf("%v: expected deletion of %v to be counted, got %v", i, tc.kind, err)
+		}
+	}
+}
+
+func TestCountDeletionIsSharedByInstances(t *testing.T) {
+	settings := &Settings{DeletionLimits: DeletionLimits{Users: 1}}
+	c := &APIClient{Settings: settings}
+	instance := &APIClient{Settings: settings}
+
+	if err := c.countDeletion("user", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := instance.countDeletion("user", "2"); err == nil {
+		t.Error("expected the limit to be shared by all instances")
+	}
+}
+
+func TestDeletionKind(t *testing.T) {
+	cases := map[string]string{
+		"Users":   "user",
+		"Groups":  "group",
+		"Schemas": "",
+	}
+
+	for endpoint, expected := range cases {
+		if actual := deletionKind(endpoint); actual != expected {
+			t.Errorf("%v: expected %q, got %q", endpoint, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Drift policies, deciding what happens when an object has been deleted outside of Terraform
+const (
+	DriftPolicyRecreate = "recreate"
+	DriftPolicyWarn     = "warn"
+	DriftPolicyFail     = "fail"
+)
+
+var driftPolicies = []string{DriftPolicyRecreate, DriftPolicyWarn, DriftPolicyFail}
+
+// handleDrift reacts to the object of d, described by what, having vanished outside of Terraform. Unless the
+// drift policy is fail, it is removed from the state, so Terraform plans to create it again.
+func handleDrift(client *APIClient, d *schema.ResourceData, what string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	detail := fmt.Sprintf("%v (ID %v) has been deleted outside of Terraform.", what, d.Id())
+
+	switch client.DriftPolicy {
+	case DriftPolicyFail:
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Object deleted outside of Terraform",
+			Detail:   detail + " drift_policy is fail, so it is not created again. Remove it from the state to create it again.",
+		})
+		return diags
+	case DriftPolicyWarn:
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Object deleted outside of Terraform",
+			Detail:   detail + " It will be created again.",
+		})
+	}
+
+	d.SetId("")
+
+	return diags
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	regionPattern = regexp.MustCompile(`^[a-z]{2}(-gov)?-[a-z]+-[0-9]+$`)
+
+	// AWS SSO SCIM endpoints look like https://scim.<region>.amazonaws.com/<tenant>/scim/v2/
+	awsHostPattern = regexp.MustCompile(`^scim\.[a-z0-9-]+\.amazonaws\.com$`)
+	awsPathPattern = regexp.MustCompile(`^/[^/]+/scim/v2/$`)
+)
+
+// buildEndpoint returns the canonical SCIM endpoint of an AWS SSO instance.
+func buildEndpoint(region string, tenantID string) (string, error) {
+	if !regionPattern.MatchString(region) {
+		return "", fmt.Errorf("invalid region %q, expected e.g. \"eu-central-1\"", region)
+	}
+
+	if tenantID == "" || strings.ContainsAny(tenantID, "/?#") {
+		return "", fmt.Errorf("invalid tenant ID %q", tenantID)
+	}
+
+	return fmt.Sprintf("https://scim.%v.amazonaws.com/%v/scim/v2/", region, tenantID), nil
+}
+
+// normalizeEndpoint parses and validates a SCIM endpoint. The returned URL always ends with a slash,
+// so paths like "Users" are resolved below the endpoint instead of replacing its last segment.
+func normalizeEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %v", endpoint, err)
+	}
+
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint %q: only https is supported", endpoint)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q: missing host", endpoint)
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: must not contain credentials, query or fragment", endpoint)
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path = u.Path + "/"
+	}
+
+	if awsHostPattern.MatchString(u.Host) && !awsPathPattern.MatchString(u.Path) {
+		return nil, fmt.Errorf("invalid endpoint %q: expected https://%v/<tenant>/scim/v2/", endpoint, u.Host)
+	}
+
+	return u, nil
+}
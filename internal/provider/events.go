@@ -0,0 +1,366 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/id"
+)
+
+const (
+	// Deliver events up to 3 times
+	DefaultEventDeliveryAttempts int = 3
+
+	// Terraform kills providers which don't exit within 2 seconds
+	EventFlushTimeout = 1500 * time.Millisecond
+
+	eventInitialBackoff = 500 * time.Millisecond
+	eventQueueSize      = 1000
+	webhookTimeout      = 10 * time.Second
+)
+
+// Event describes a change of the directory, e.g. "user.created" or "group.member.added".
+type Event struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Time       string            `json:"time"`
+	Endpoint   string            `json:"endpoint"`
+	ObjectID   string            `json:"object_id,omitempty"`
+	NaturalKey string            `json:"natural_key,omitempty"`
+	GroupID    string            `json:"group_id,omitempty"`
+	UserID     string            `json:"user_id,omitempty"`
+	Terraform  map[string]string `json:"terraform,omitempty"`
+}
+
+// EventSink receives the events of successful writes.
+type EventSink interface {
+	Send(event []byte) error
+	String() string
+}
+
+func expandEventSink(m map[string]interface{}) (EventSink, error) {
+	switch m["type"].(string) {
+	case "webhook":
+		if m["url"].(string) == "" {
+			return nil, fmt.Errorf("event sink of type webhook requires url")
+		}
+		return NewWebhookEventSink(m["url"].(string), m["hmac_secret"].(string)), nil
+	case "file":
+		if m["path"].(string) == "" {
+			return nil, fmt.Errorf("event sink of type file requires path")
+		}
+		return NewFileEventSink(m["path"].(string))
+	default:
+		return NewStdoutEventSink(), nil
+	}
+}
+
+// webhookSink posts events to a URL. With a secret, the hex encoded HMAC-SHA256 of the body is sent
+// in the X-Signature-256 header, prefixed with "sha256=".
+type webhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookEventSink(url string, secret string) EventSink {
+	return &webhookSink{url: url, secret: secret, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (s *webhookSink) Send(event []byte) error {
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(event))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if s.secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.secret))
+		mac.Write(event)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected HTTP status code: %v", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *webhookSink) String() string {
+	return fmt.Sprintf("webhook %v", s.url)
+}
+
+// writerSink writes events as lines of JSON, e.g. to a file or stdout.
+type writerSink struct {
+	name string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewFileEventSink(path string) (EventSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open event file: %v", err)
+	}
+	return &writerSink{name: fmt.Sprintf("file %v", path), w: file}, nil
+}
+
+// NewStdoutEventSink writes events to the standard output of the provider, which Terraform includes in its logs.
+func NewStdoutEventSink() EventSink {
+	return &writerSink{name: "stdout", w: os.Stdout}
+}
+
+func (s *writerSink) Send(event []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.w.Write(append(event, '\n'))
+	return err
+}
+
+func (s *writerSink) String() string {
+	return s.name
+}
+
+var memberFilterPath = regexp.MustCompile(`^members\[value eq "([^"]*)"\]$`)
+
+// events derives the events of a successful mutating request.
+func (c *APIClient) events(method, path string, body interface{}, v interface{}) []Event {
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+
+	var kind string
+	switch strings.SplitN(path, "/", 2)[0] {
+	case "Users":
+		kind = "user"
+	case "Groups":
+		kind = "group"
+	case "Bulk":
+		// the operations of bulk requests are not reported
+		return nil
+	default:
+		kind = "resource"
+	}
+
+	event := Event{
+		Endpoint:   c.BaseURL.String(),
+		ObjectID:   auditObjectID(path, v),
+		NaturalKey: c.auditNaturalKey(path, body),
+	}
+
+	switch method {
+	case "POST":
+		event.Type = kind + ".created"
+	case "DELETE":
+		event.Type = kind + ".deleted"
+	case "PUT":
+		event.Type = kind + ".updated"
+	case "PATCH":
+		if kind == "group" {
+			return groupPatchEvents(event, body)
+		}
+		event.Type = kind + ".updated"
+	default:
+		return nil
+	}
+
+	return []Event{event}
+}
+
+// groupPatchEvents reports added and removed members of a group separately from other changes.
+func groupPatchEvents(event Event, body interface{}) []Event {
+	var opmsg OperationMessage
+	if encoded, err := json.Marshal(body); err == nil {
+		json.Unmarshal(encoded, &opmsg)
+	}
+
+	events := []Event{}
+	updated := false
+
+	for _, op := range opmsg.Operations {
+		operation := strings.ToLower(op.Operation)
+
+		if !strings.HasPrefix(strings.ToLower(op.Path), "members") || (operation != "add" && operation != "remove") {
+			updated = true
+			continue
+		}
+
+		members := memberIDs(op.Value)
+		if m := memberFilterPath.FindStringSubmatch(op.Path); m != nil {
+			members = append(members, m[1])
+		}
+
+		for _, member := range members {
+			memberEvent := event
+			memberEvent.GroupID = event.ObjectID
+			memberEvent.UserID = member
+			if operation == "add" {
+				memberEvent.Type = "group.member.added"
+			} else {
+				memberEvent.Type = "group.member.removed"
+			}
+			events = append(events, memberEvent)
+		}
+	}
+
+	if updated || len(opmsg.Operations) == 0 {
+		event.Type = "group.updated"
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// publish queues the events of a successful mutating request for all sinks. Delivery happens in the background,
+// failed deliveries are retried and finally reported as warning, they never fail or delay the request.
+func (c *APIClient) publish(method, path string, body interface{}, v interface{}) {
+	if len(c.EventQueues) == 0 {
+		return
+	}
+
+	for _, event := range c.events(method, path, body, v) {
+		event.ID = id.UniqueId()
+		event.Time = time.Now().UTC().Format(time.RFC3339Nano)
+		event.Terraform = terraformMetadata()
+
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+
+		description := fmt.Sprintf("The %v event of %v %v", event.Type, method, path)
+		for _, q := range c.EventQueues {
+			q.enqueue(encoded, description, c.warn)
+		}
+	}
+}
+
+// warn adds a warning to the diagnostics of background work, reported by the next resource operation.
+func (c *APIClient) warn(summary string, detail string) {
+	c.background.add(diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  summary,
+		Detail:   detail,
+	})
+}
+
+// EventQueue delivers events to a sink in the background. Events which don't fit into the queue are dropped.
+type EventQueue struct {
+	sink     EventSink
+	attempts int
+	events   chan queuedEvent
+	pending  sync.WaitGroup
+}
+
+type queuedEvent struct {
+	event       []byte
+	description string
+	warn        func(summary string, detail string)
+}
+
+// all queues of the process, flushed by FlushEvents before it exits
+var eventQueues struct {
+	mu     sync.Mutex
+	queues []*EventQueue
+}
+
+func NewEventQueue(sink EventSink, attempts int) *EventQueue {
+	q := &EventQueue{
+		sink:     sink,
+		attempts: attempts,
+		events:   make(chan queuedEvent, eventQueueSize),
+	}
+	go q.run()
+
+	eventQueues.mu.Lock()
+	eventQueues.queues = append(eventQueues.queues, q)
+	eventQueues.mu.Unlock()
+
+	return q
+}
+
+func (q *EventQueue) enqueue(event []byte, description string, warn func(summary string, detail string)) {
+	q.pending.Add(1)
+
+	select {
+	case q.events <- queuedEvent{event: event, description: description, warn: warn}:
+	default:
+		q.pending.Done()
+		warn("Unable to deliver event", fmt.Sprintf("%v has been dropped, because %v events are waiting for delivery to %v already.", description, eventQueueSize, q.sink))
+	}
+}
+
+func (q *EventQueue) run() {
+	for e := range q.events {
+		if err := deliver(q.sink, e.event, q.attempts); err != nil {
+			log.Printf("[WARN] %v could not be delivered to %v: %v", e.description, q.sink, err)
+			e.warn("Unable to deliver event", fmt.Sprintf("%v could not be delivered to %v: %v", e.description, q.sink, err))
+		}
+		q.pending.Done()
+	}
+}
+
+// FlushEvents waits up to timeout for all queued events to be delivered. It is called before the provider exits.
+func FlushEvents(timeout time.Duration) {
+	eventQueues.mu.Lock()
+	queues := eventQueues.queues
+	eventQueues.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for _, q := range queues {
+			q.pending.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		for _, q := range queues {
+			if n := len(q.events); n > 0 {
+				log.Printf("[WARN] %v events could not be delivered to %v before the provider exited", n, q.sink)
+			}
+		}
+	}
+}
+
+func deliver(sink EventSink, event []byte, attempts int) error {
+	backoff := eventInitialBackoff
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = sink.Send(event); err == nil {
+			return nil
+		}
+		if attempt < attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return err
+}
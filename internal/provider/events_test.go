@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSink records events and fails the first failures sends, each after delay.
+type testSink struct {
+	mu       sync.Mutex
+	delay    time.Duration
+	failures int
+	events   []string
+}
+
+func (s *testSink) Send(event []byte) error {
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink is down")
+	}
+	s.events = append(s.events, string(event))
+	return nil
+}
+
+func (s *testSink) String() string {
+	return "test sink"
+}
+
+func newEventTestClient(queues ...*EventQueue) *APIClient {
+	baseURL, _ := url.Parse("https://example.com/scim/v2/")
+	return &APIClient{
+		Settings:    &Settings{EventQueues: queues},
+		BaseURL:     baseURL,
+		clientState: &clientState{},
+	}
+}
+
+func TestPublishDoesNotWaitForDelivery(t *testing.T) {
+	sink := &testSink{delay: 200 * time.Millisecond}
+	c := newEventTestClient(NewEventQueue(sink, 1))
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		c.publish("POST", "Users", &User{UserName: "jdoe"}, &User{ID: "1"})
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected publish to return immediately, took %v", elapsed)
+	}
+
+	FlushEvents(5 * time.Second)
+
+	if len(sink.events) != 5 || !strings.Contains(sink.events[0], `"type":"user.created"`) {
+		t.Errorf("expected 5 delivered events, got %v", sink.events)
+	}
+}
+
+func TestFailedDeliveryIsRetriedAndReported(t *testing.T) {
+	retried := &testSink{failures: 1}
+	failed := &testSink{failures: 2}
+	c := newEventTestClient(NewEventQueue(retried, 2), NewEventQueue(failed, 2))
+
+	c.publish("DELETE", "Groups/1", nil, nil)
+	FlushEvents(5 * time.Second)
+
+	if len(retried.events) != 1 {
+		t.Errorf("expected event to be delivered on the second attempt, got %v", retried.events)
+	}
+
+	diags := c.drainDiagnostics()
+	if len(diags) != 1 || diags[0].Summary != "Unable to deliver event" || diags[0].Severity != 1 {
+		t.Errorf("expected a single warning, got %v", diags)
+	}
+}
+
+func TestFullQueueDropsEvents(t *testing.T) {
+	blocked := make(chan struct{})
+	sink := &blockingSink{blocked: blocked}
+	q := NewEventQueue(sink, 1)
+	c := newEventTestClient(q)
+
+	// one event is being delivered, the others fill the queue
+	for i := 0; i < eventQueueSize+2; i++ {
+		c.publish("DELETE", "Users/1", nil, nil)
+	}
+	close(blocked)
+	FlushEvents(5 * time.Second)
+
+	diags := c.drainDiagnostics()
+	if len(diags) == 0 || !strings.Contains(diags[0].Detail, "has been dropped") {
+		t.Errorf("expected a warning about dropped events, got %v", diags)
+	}
+}
+
+type blockingSink struct {
+	blocked chan struct{}
+}
+
+func (s *blockingSink) Send(event []byte) error {
+	<-s.blocked
+	return nil
+}
+
+func (s *blockingSink) String() string {
+	return "blocking sink"
+}
+
+func TestWebhookSignature(t *testing.T) {
+	received := make(chan bool, 1)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		received <- r.Header.Get("X-Signature-256") == "sha256="+hex.EncodeToString(mac.Sum(nil))
+	})
+
+	sink := NewWebhookEventSink(c.BaseURL.String(), "secret").(*webhookSink)
+	sink.client = &http.Client{Transport: c.httpClient.client.Transport}
+
+	if err := sink.Send([]byte(`{"type":"user.created"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if !<-received {
+		t.Error("expected a valid signature")
+	}
+}
+
+func TestGroupPatchEvents(t *testing.T) {
+	c := newEventTestClient()
+
+	events := c.events("PATCH", "Groups/g-1", OperationMessage{Operations: []Operation{
+		{Operation: "add", Path: "members", Value: []map[string]string{{"value": "u-1"}, {"value": "u-2"}}},
+		{Operation: "remove", Path: `members[value eq "u-3"]`},
+		{Operation: "replace", Path: "displayName", Value: "admins"},
+	}}, nil)
+
+	types := []string{}
+	for _, e := range events {
+		types = append(types, e.Type+" "+e.UserID)
+	}
+
+	expected := "group.member.added u-1,group.member.added u-2,group.member.removed u-3,group.updated "
+	if strings.Join(types, ",") != expected {
+		t.Errorf("expected events %v, got %v", expected, strings.Join(types, ","))
+	}
+}
@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// Scheduled freeze windows may last up to 31 days
+const maxFreezeDuration = 31 * 24 * time.Hour
+
+// FreezeWindow is a period during which nothing may be changed. It is either the fixed range from Start to End,
+// or starts at every time matching Schedule in Location and lasts for Duration.
+type FreezeWindow struct {
+	Name string
+
+	Start time.Time
+	End   time.Time
+
+	Schedule *cronSchedule
+	Duration time.Duration
+	Location *time.Location
+}
+
+func expandFreezeWindow(m map[string]interface{}) (FreezeWindow, error) {
+	w := FreezeWindow{
+		Name: m["name"].(string),
+	}
+
+	start, end := m["start"].(string), m["end"].(string)
+	schedule, duration := m["schedule"].(string), m["duration"].(string)
+
+	switch {
+	case start != "" && end != "" && schedule == "" && duration == "":
+		// already checked by validation.IsRFC3339Time
+		w.Start, _ = time.Parse(time.RFC3339, start)
+		w.End, _ = time.Parse(time.RFC3339, end)
+		if !w.End.After(w.Start) {
+			return w, fmt.Errorf("freeze window %q ends before it starts", w.Name)
+		}
+	case schedule != "" && duration != "" && start == "" && end == "":
+		var err error
+		if w.Schedule, err = parseCronSchedule(schedule); err != nil {
+			return w, fmt.Errorf("freeze window %q: %v", w.Name, err)
+		}
+		if w.Duration, err = time.ParseDuration(duration); err != nil {
+			return w, fmt.Errorf("freeze window %q: invalid duration: %v", w.Name, err)
+		}
+		if w.Duration <= 0 || w.Duration > maxFreezeDuration {
+			return w, fmt.Errorf("freeze window %q: duration has to be positive and at most %v", w.Name, maxFreezeDuration)
+		}
+		if w.Location, err = time.LoadLocation(m["timezone"].(string)); err != nil {
+			return w, fmt.Errorf("freeze window %q: invalid timezone: %v", w.Name, err)
+		}
+	default:
+		return w, fmt.Errorf("freeze window %q requires either start and end, or schedule and duration", w.Name)
+	}
+
+	return w, nil
+}
+
+// activeAt returns the end of the window if it is active at t.
+func (w FreezeWindow) activeAt(t time.Time) (time.Time, bool) {
+	if w.Schedule == nil {
+		return w.End, !t.Before(w.Start) && t.Before(w.End)
+	}
+
+	t = t.In(w.Location)
+	start := t.Truncate(time.Minute)
+	for !start.Before(t.Add(-w.Duration)) {
+		if w.Schedule.matches(start) && t.Before(start.Add(w.Duration)) {
+			return start.Add(w.Duration), true
+		}
+		start = start.Add(-time.Minute)
+	}
+
+	return time.Time{}, false
+}
+
+// cronSchedule matches times like a crontab entry with the fields minute, hour, day of month, month and day of week.
+type cronSchedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek map[int]bool
+
+	// like cron, a time matches either restricted day field if both are restricted
+	daysOfMonthRestricted, daysOfWeekRestricted bool
+}
+
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q has %v fields, expected 5: minute, hour, day of month, month and day of week", spec, len(fields))
+	}
+
+	s := &cronSchedule{}
+	var err error
+
+	if s.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute in schedule %q: %v", spec, err)
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour in schedule %q: %v", spec, err)
+	}
+	if s.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month in schedule %q: %v", spec, err)
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month in schedule %q: %v", spec, err)
+	}
+	if s.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week in schedule %q: %v", spec, err)
+	}
+
+	// both 0 and 7 are Sunday
+	if s.daysOfWeek[7] {
+		s.daysOfWeek[0] = true
+	}
+
+	s.daysOfMonthRestricted = fields[2] != "*"
+	s.daysOfWeekRestricted = fields[4] != "*"
+
+	return s, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges like 1-5 and steps like */15 or 0-30/10.
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is out of range %v-%v", part, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (s *cronSchedule) matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+
+	dayOfMonth := s.daysOfMonth[t.Day()]
+	dayOfWeek := s.daysOfWeek[int(t.Weekday())]
+
+	if s.daysOfMonthRestricted && s.daysOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// refuseFrozen returns an error if a freeze window is active, unless freeze_override_reason is set.
+// Overrides are logged and reported as warning once per window.
+func (c *APIClient) refuseFrozen(action string) error {
+	w, end, active := c.activeFreezeWindow(time.Now())
+	if !active {
+		return nil
+	}
+
+	if c.FreezeOverrideReason == "" {
+		return fmt.Errorf("change freeze %q is in effect until %v, refusing to %v. Set freeze_override_reason to override", w.Name, end.Format(time.RFC3339), action)
+	}
+
+	log.Printf("[WARN] change freeze %q overridden to %v: %v", w.Name, action, c.FreezeOverrideReason)
+	c.warnFreezeOverride(w.Name)
+
+	return nil
+}
+
+// activeFreezeWindow returns the first window active at now and its end.
+func (c *APIClient) activeFreezeWindow(now time.Time) (FreezeWindow, time.Time, bool) {
+	for _, w := range c.FreezeWindows {
+		if end, active := w.activeAt(now); active {
+			return w, end, true
+		}
+	}
+	return FreezeWindow{}, time.Time{}, false
+}
+
+func (c *APIClient) warnFreezeOverride(name string) {
+	c.warnedMu.Lock()
+	defer c.warnedMu.Unlock()
+
+	if c.freezeOverrides == nil {
+		c.freezeOverrides = map[string]bool{}
+	}
+	if c.freezeOverrides[name] {
+		return
+	}
+	c.freezeOverrides[name] = true
+
+	c.diags.add(diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  "Change freeze overridden",
+		Detail:   fmt.Sprintf("The change freeze %q is in effect and has been overridden: %v", name, c.FreezeOverrideReason),
+	})
+}
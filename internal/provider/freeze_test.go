@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	cases := []struct {
+		field    string
+		min, max int
+		expected []int
+	}{
+		{field: "*", min: 0, max: 5, expected: []int{0, 1, 2, 3, 4, 5}},
+		{field: "1,3", min: 0, max: 5, expected: []int{1, 3}},
+		{field: "1-3", min: 0, max: 5, expected: []int{1, 2, 3}},
+		{field: "*/15", min: 0, max: 59, expected: []int{0, 15, 30, 45}},
+		{field: "0-30/10", min: 0, max: 59, expected: []int{0, 10, 20, 30}},
+		{field: "1-5/2,7", min: 1, max: 7, expected: []int{1, 3, 5, 7}},
+		{field: "60", min: 0, max: 59},
+		{field: "0", min: 1, max: 31},
+		{field: "5-1", min: 0, max: 59},
+		{field: "*/0", min: 0, max: 59},
+		{field: "a", min: 0, max: 59},
+		{field: "1-b", min: 0, max: 59},
+	}
+
+	for _, tc := range cases {
+		values, err := parseCronField(tc.field, tc.min, tc.max)
+
+		if tc.expected == nil {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", tc.field, values)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.field, err)
+			continue
+		}
+
+		actual := []int{}
+		for v := range values {
+			actual = append(actual, v)
+		}
+		sort.Ints(actual)
+
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%q: expected %v, got %v", tc.field, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseCronSchedule(t *testing.T) {
+	if _, err := parseCronSchedule("0 0 * *"); err == nil {
+		t.Error("expected schedules with 4 fields to be invalid")
+	}
+
+	s, err := parseCronSchedule("0 0 * * 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.matches(time.Date(2024, 10, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Error("expected 7 to match Sunday")
+	}
+}
+
+func mustFreezeWindow(t *testing.T, schedule string, duration string, timezone string) FreezeWindow {
+	t.Helper()
+
+	w, err := expandFreezeWindow(map[string]interface{}{
+		"name":     "test",
+		"start":    "",
+		"end":      "",
+		"schedule": schedule,
+		"duration": duration,
+		"timezone": timezone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w
+}
+
+func TestFreezeWindowActiveAt(t *testing.T) {
+	fixed := FreezeWindow{
+		Name:  "fixed",
+		Start: time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 3, 26, 0, 0, 0, 0, time.UTC),
+	}
+	endOfYear := mustFreezeWindow(t, "0 0 20 12 *", "336h", "UTC")
+	thirteenthOrFriday := mustFreezeWindow(t, "0 0 13 * 5", "24h", "UTC")
+	mondays := mustFreezeWindow(t, "0 0 * * 1", "24h", "UTC")
+	berlinNights := mustFreezeWindow(t, "0 0 * * *", "2h", "Europe/Berlin")
+
+	cases := []struct {
+		name        string
+		window      FreezeWindow
+		t           string
+		active      bool
+		expectedEnd string
+	}{
+		{"fixed, before", fixed, "2024-03-24T23:59:59Z", false, ""},
+		{"fixed, at start", fixed, "2024-03-25T00:00:00Z", true, "2024-03-26T00:00:00Z"},
+		{"fixed, at end", fixed, "2024-03-26T00:00:00Z", false, ""},
+		{"end of year, before", endOfYear, "2024-12-19T23:59:00Z", false, ""},
+		{"end of year, across new year", endOfYear, "2025-01-01T12:00:00Z", true, "2025-01-03T00:00:00Z"},
+		{"end of year, at end", endOfYear, "2025-01-03T00:00:00Z", false, ""},
+		// a time matches either day field if both are restricted
+		{"13th, on a Sunday", thirteenthOrFriday, "2024-10-13T12:00:00Z", true, "2024-10-14T00:00:00Z"},
+		{"Friday, on the 6th", thirteenthOrFriday, "2024-09-06T12:00:00Z", true, "2024-09-07T00:00:00Z"},
+		{"neither 13th nor Friday", thirteenthOrFriday, "2024-10-14T12:00:00Z", false, ""},
+		// and both if only one is restricted
+		{"Monday", mondays, "2024-10-14T12:00:00Z", true, "2024-10-15T00:00:00Z"},
+		{"Tuesday", mondays, "2024-10-15T12:00:00Z", false, ""},
+		// the schedule is matched in its time zone, the duration is elapsed time, also across DST changes
+		{"Berlin, before midnight in UTC", berlinNights, "2024-10-13T23:30:00Z", true, "2024-10-14T00:00:00Z"},
+		{"Berlin, midnight in UTC", berlinNights, "2024-10-14T00:30:00Z", false, ""},
+		{"Berlin, before DST starts", berlinNights, "2024-03-31T00:59:00Z", true, "2024-03-31T01:00:00Z"},
+		{"Berlin, after DST started", berlinNights, "2024-03-31T01:30:00Z", false, ""},
+		{"Berlin, in summer", berlinNights, "2024-07-01T23:30:00Z", true, "2024-07-02T00:00:00Z"},
+	}
+
+	for _, tc := range cases {
+		at, _ := time.Parse(time.RFC3339, tc.t)
+		end, active := tc.window.activeAt(at)
+
+		if active != tc.active {
+			t.Errorf("%v: expected active %v at %v", tc.name, tc.active, tc.t)
+			continue
+		}
+		if active && !end.Equal(mustParseTime(tc.expectedEnd)) {
+			t.Errorf("%v: expected end %v, got %v", tc.name, tc.expectedEnd, end.UTC().Format(time.RFC3339))
+		}
+	}
+}
+
+func mustParseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestExpandFreezeWindowErrors(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"start": "2024-03-26T00:00:00Z", "end": "2024-03-25T00:00:00Z"},
+		{"start": "2024-03-25T00:00:00Z", "end": "2024-03-26T00:00:00Z", "schedule": "0 0 * * *", "duration": "1h"},
+		{},
+		{"schedule": "0 0 * * *", "duration": "745h"},
+		{"schedule": "0 0 * * *", "duration": "-1h"},
+		{"schedule": "0 0 * * *", "duration": "1h", "timezone": "Mars/Olympus_Mons"},
+		{"schedule": "0 24 * * *", "duration": "1h"},
+	}
+
+	for i, tc := range cases {
+		m := map[string]interface{}{"name": "test", "start": "", "end": "", "schedule": "", "duration": "", "timezone": "UTC"}
+		for k, v := range tc {
+			m[k] = v
+		}
+
+		if _, err := expandFreezeWindow(m); err == nil {
+			t.Errorf("%v: expected %v to be invalid", i, tc)
+		}
+	}
+}
+
+func TestRefuseFrozen(t *testing.T) {
+	c := &APIClient{Settings: &Settings{
+		FreezeWindows: []FreezeWindow{{
+			Name:  "always",
+			Start: time.Now().Add(-time.Hour),
+			End:   time.Now().Add(time.Hour),
+		}},
+	}, clientState: &clientState{}}
+
+	if err := c.refuseFrozen("create it"); err == nil || !strings.Contains(err.Error(), "freeze_override_reason") {
+		t.Errorf("expected change to be refused, got %v", err)
+	}
+
+	c.FreezeOverrideReason = "INC-1234"
+	for i := 0; i < 2; i++ {
+		if err := c.refuseFrozen("create it"); err != nil {
+			t.Errorf("expected override to be accepted, got %v", err)
+		}
+	}
+
+	if diags := c.drainDiagnostics(); len(diags) != 1 || !strings.Contains(diags[0].Detail, "INC-1234") {
+		t.Errorf("expected a single warning about the override, got %v", diags)
+	}
+}
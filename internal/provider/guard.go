@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// attributes naming a resource in diagnostics, in order of preference
+var naturalKeyAttributes = []string{"user_name", "display_name", "endpoint", "resource_type"}
+
+// describeResource names the resource d of type name for diagnostics, e.g. `aws-sso-scim_user "jane" (ID 1234)`.
+func describeResource(name string, r *schema.Resource, d *schema.ResourceData) string {
+	description := name
+
+	for _, k := range naturalKeyAttributes {
+		if _, ok := r.Schema[k]; ok {
+			if v, ok := d.Get(k).(string); ok && v != "" {
+				description = fmt.Sprintf("%v %q", description, v)
+				break
+			}
+		}
+	}
+
+	if d.Id() != "" {
+		description = fmt.Sprintf("%v (ID %v)", description, d.Id())
+	}
+
+	return description
+}
+
+// withWriteGuard makes the create, update and delete functions of r fail if the provider is read-only
+// or a change freeze is in effect.
+func withWriteGuard(name string, r *schema.Resource) {
+	type crudFunc = func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics
+
+	wrap := func(action string, f crudFunc) crudFunc {
+		if f == nil {
+			return nil
+		}
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			client, ok := meta.(*APIClient)
+			if ok && client.ReadOnly {
+				return diag.Diagnostics{
+					{
+						Severity: diag.Error,
+						Summary:  "Provider is read-only",
+						Detail:   fmt.Sprintf("read_only is enabled, refusing to %v %v.", action, describeResource(name, r, d)),
+					},
+				}
+			}
+			if ok {
+				if err := client.refuseFrozen(fmt.Sprintf("%v %v", action, describeResource(name, r, d))); err != nil {
+					return diag.Diagnostics{
+						{
+							Severity: diag.Error,
+							Summary:  "Change freeze in effect",
+							Detail:   err.Error() + ".",
+						},
+					}
+				}
+			}
+			return f(ctx, d, meta)
+		}
+	}
+
+	r.CreateContext = wrap("create", r.CreateContext)
+	r.UpdateContext = wrap("update", r.UpdateContext)
+	r.DeleteContext = wrap("delete", r.DeleteContext)
+}
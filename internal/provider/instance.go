@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// withInstanceArgument adds the instance argument to r. Changing the instance of a resource replaces it.
+// Resources can be imported from an instance by prefixing the import ID with "INSTANCE/".
+func withInstanceArgument(r *schema.Resource, forceNew bool) {
+	r.Schema["instance"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Description: "Name of the instance configured in the `instances` block of the provider. Defaults to the endpoint and token configured at the top level of the provider.",
+		Optional:    true,
+		ForceNew:    forceNew,
+	}
+
+	if r.Importer == nil || r.Importer.StateContext == nil {
+		return
+	}
+
+	importer := r.Importer.StateContext
+	r.Importer.StateContext = func(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+		if client, ok := meta.(*APIClient); ok {
+			if name, importID, found := strings.Cut(d.Id(), "/"); found {
+				if _, ok := client.Instances[name]; ok {
+					d.Set("instance", name)
+					d.SetId(importID)
+				}
+			}
+		}
+		return importer(ctx, d, meta)
+	}
+}
+
+// clientFor returns the client of the instance selected by d.
+func clientFor(d interface{ Get(string) interface{} }, meta interface{}) (*APIClient, error) {
+	name, _ := d.Get("instance").(string)
+	return meta.(*APIClient).Instance(name)
+}
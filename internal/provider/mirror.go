@@ -0,0 +1,400 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+const (
+	MirrorModeWarn = "warn"
+	MirrorModeFail = "fail"
+)
+
+var mirrorModes = []string{MirrorModeWarn, MirrorModeFail}
+
+// Mirror replays every create, update and delete of a client on a second SCIM endpoint.
+type Mirror struct {
+	client  *APIClient
+	mode    string
+	mapping *mirrorMapping
+}
+
+// mirrorEntry maps the ID of an object to the ID of its copy on the mirror.
+type mirrorEntry struct {
+	ID       string `json:"id"`
+	MirrorID string `json:"mirror_id"`
+}
+
+// mirrorMapping is persisted as JSON in a file, by endpoint and natural key, i.e. the userName of users
+// and the displayName of groups. Objects of other endpoints are keyed by their ID.
+type mirrorMapping struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]map[string]mirrorEntry
+}
+
+func NewMirror(client *APIClient, mode string, mappingFile string) (*Mirror, error) {
+	mapping := &mirrorMapping{path: mappingFile, entries: map[string]map[string]mirrorEntry{}}
+
+	content, err := os.ReadFile(mappingFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("unable to read mirror mapping_file: %v", err)
+	default:
+		if err := json.Unmarshal(content, &mapping.entries); err != nil {
+			return nil, fmt.Errorf("unable to parse mirror mapping_file %v: %v", mappingFile, err)
+		}
+	}
+
+	return &Mirror{client: client, mode: mode, mapping: mapping}, nil
+}
+
+// lookup returns the mirror ID of the object at endpoint with id.
+func (m *mirrorMapping) lookup(endpoint string, id string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.entries[endpoint] {
+		if e.ID == id {
+			return e.MirrorID, true
+		}
+	}
+	return "", false
+}
+
+// record maps id to mirrorID under naturalKey, replacing earlier entries of either ID.
+func (m *mirrorMapping) record(endpoint string, naturalKey string, id string, mirrorID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if naturalKey == "" {
+		naturalKey = id
+	}
+
+	entries := m.entries[endpoint]
+	if entries == nil {
+		entries = map[string]mirrorEntry{}
+		m.entries[endpoint] = entries
+	}
+	for k, e := range entries {
+		if e.ID == id || e.MirrorID == mirrorID {
+			delete(entries, k)
+		}
+	}
+	entries[naturalKey] = mirrorEntry{ID: id, MirrorID: mirrorID}
+
+	return m.save()
+}
+
+func (m *mirrorMapping) forget(endpoint string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k, e := range m.entries[endpoint] {
+		if e.ID == id {
+			delete(m.entries[endpoint], k)
+		}
+	}
+
+	return m.save()
+}
+
+// save replaces the mapping file, so it is never left half written.
+func (m *mirrorMapping) save() error {
+	content, err := json.MarshalIndent(m.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return fmt.Errorf("unable to write mirror mapping_file: %v", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("unable to write mirror mapping_file: %v", err)
+	}
+
+	return nil
+}
+
+// mirrorNaturalKey returns the userName of users and the displayName of groups in object.
+func mirrorNaturalKey(endpoint string, object interface{}) string {
+	if object == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return ""
+	}
+	var keys struct {
+		UserName    string `json:"userName"`
+		DisplayName string `json:"displayName"`
+	}
+	json.Unmarshal(encoded, &keys)
+
+	switch endpoint {
+	case "Users":
+		return keys.UserName
+	case "Groups":
+		return keys.DisplayName
+	}
+	return ""
+}
+
+// patchedNaturalKey returns the natural key set by a PATCH request, if it changes it.
+func patchedNaturalKey(endpoint string, body interface{}) string {
+	var opmsg OperationMessage
+	if encoded, err := json.Marshal(body); err == nil {
+		json.Unmarshal(encoded, &opmsg)
+	}
+
+	key := ""
+	for _, op := range opmsg.Operations {
+		if strings.ToLower(op.Operation) != "replace" && strings.ToLower(op.Operation) != "add" {
+			continue
+		}
+		if op.Path == "" {
+			if k := mirrorNaturalKey(endpoint, op.Value); k != "" {
+				key = k
+			}
+			continue
+		}
+		if value, ok := op.Value.(string); ok && mirrorNaturalKey(endpoint, map[string]string{op.Path: value}) != "" {
+			key = value
+		}
+	}
+	return key
+}
+
+// translate returns the mirror ID of the object at endpoint with id. Objects missing in the mapping,
+// e.g. created before mirroring was enabled, are looked up on the mirror by their natural key.
+func (c *APIClient) translate(endpoint string, id string) (string, error) {
+	m := c.Mirror
+
+	if mirrorID, ok := m.mapping.lookup(endpoint, id); ok {
+		return mirrorID, nil
+	}
+
+	if endpoint != "Users" && endpoint != "Groups" {
+		return "", fmt.Errorf("%v/%v is not in the mirror mapping_file", endpoint, id)
+	}
+
+	var object map[string]interface{}
+	if _, err := c.doRequest("GET", fmt.Sprintf("%v/%v", endpoint, id), "", nil, &object); err != nil {
+		return "", fmt.Errorf("unable to read %v/%v to find it on the mirror: %v", endpoint, id, err)
+	}
+
+	naturalKey := mirrorNaturalKey(endpoint, object)
+	mirrorID, err := m.findByNaturalKey(endpoint, naturalKey)
+	if err != nil {
+		return "", err
+	}
+
+	return mirrorID, m.mapping.record(endpoint, naturalKey, id, mirrorID)
+}
+
+func (m *Mirror) findByNaturalKey(endpoint string, naturalKey string) (string, error) {
+	switch endpoint {
+	case "Users":
+		user, _, err := m.client.FindUserByUsername(naturalKey)
+		if err != nil {
+			return "", fmt.Errorf("unable to find user %q on the mirror: %v", naturalKey, err)
+		}
+		return user.ID, nil
+	case "Groups":
+		group, _, err := m.client.FindGroupByDisplayname(naturalKey)
+		if err != nil {
+			return "", fmt.Errorf("unable to find group %q on the mirror: %v", naturalKey, err)
+		}
+		return group.ID, nil
+	}
+	return "", fmt.Errorf("objects of %v can not be found on the mirror by natural key", endpoint)
+}
+
+var mirrorMemberFilterPath = regexp.MustCompile(`^(members\[value eq ")([^"]*)("\].*)$`)
+
+// translateBody returns a copy of body with the user IDs of group members replaced by their mirror IDs.
+func (c *APIClient) translateBody(endpoint string, body interface{}) (interface{}, error) {
+	if body == nil || endpoint != "Groups" {
+		return body, nil
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil, err
+	}
+
+	if err := c.translateMembers(generic["members"]); err != nil {
+		return nil, err
+	}
+
+	operations, _ := generic["Operations"].([]interface{})
+	for _, v := range operations {
+		op, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		path, _ := op["path"].(string)
+		if parts := mirrorMemberFilterPath.FindStringSubmatch(path); parts != nil {
+			mirrorID, err := c.translate("Users", parts[2])
+			if err != nil {
+				return nil, err
+			}
+			op["path"] = parts[1] + mirrorID + parts[3]
+		}
+
+		if strings.HasPrefix(strings.ToLower(path), "members") {
+			if err := c.translateMembers(op["value"]); err != nil {
+				return nil, err
+			}
+		} else if values, ok := op["value"].(map[string]interface{}); ok && path == "" {
+			if err := c.translateMembers(values["members"]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return generic, nil
+}
+
+func (c *APIClient) translateMembers(members interface{}) error {
+	list, _ := members.([]interface{})
+	for _, v := range list {
+		member, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, ok := member["value"].(string)
+		if !ok {
+			continue
+		}
+		mirrorID, err := c.translate("Users", id)
+		if err != nil {
+			return err
+		}
+		member["value"] = mirrorID
+	}
+	return nil
+}
+
+// mirrorRequest is a mutating request to be replayed on the mirror once it succeeded.
+type mirrorRequest struct {
+	method   string
+	endpoint string
+	id       string
+	body     interface{}
+
+	// mirror ID of the object to delete, looked up before the request, as the object is gone afterwards
+	mirrorID string
+	err      error
+}
+
+// prepareReplication is called before sending a mutating request, see replicate.
+func (c *APIClient) prepareReplication(method, path string, body interface{}) *mirrorRequest {
+	r := &mirrorRequest{method: method, endpoint: path, body: body}
+	if i := strings.Index(path, "/"); i >= 0 {
+		r.endpoint, r.id = path[:i], path[i+1:]
+	}
+
+	if method == "DELETE" && r.endpoint != "Bulk" {
+		r.mirrorID, r.err = c.translate(r.endpoint, r.id)
+	}
+
+	return r
+}
+
+// replicate replays a successful mutating request on the mirror. Failures are reported as warning
+// or error of the resource operation which sent the request, depending on the mode of the mirror.
+// The request itself has been applied already.
+func (c *APIClient) replicate(r *mirrorRequest, v interface{}) {
+	if err := c.replay(r, v); err != nil {
+		severity := diag.Warning
+		if c.Mirror.mode == MirrorModeFail {
+			severity = diag.Error
+		}
+
+		c.diags.add(diag.Diagnostic{
+			Severity: severity,
+			Summary:  "Unable to mirror change",
+			Detail:   fmt.Sprintf("%v %v/%v has been applied, but not on the mirror %v: %v", r.method, r.endpoint, r.id, c.Mirror.client.BaseURL, err),
+		})
+	}
+}
+
+func (c *APIClient) replay(r *mirrorRequest, v interface{}) error {
+	m := c.Mirror
+	endpoint, id := r.endpoint, r.id
+
+	if endpoint == "Bulk" {
+		return fmt.Errorf("bulk requests are not mirrored")
+	}
+
+	translated, err := c.translateBody(endpoint, r.body)
+	if err != nil {
+		return err
+	}
+
+	switch r.method {
+	case "POST":
+		naturalKey := mirrorNaturalKey(endpoint, r.body)
+
+		var created map[string]interface{}
+		resp, err := m.client.doRequest("POST", endpoint, "", translated, &created)
+
+		mirrorID, _ := created["id"].(string)
+		if err != nil && resp != nil && resp.StatusCode == 409 && naturalKey != "" {
+			// the object exists on the mirror already, e.g. because it was created there by hand
+			mirrorID, err = m.findByNaturalKey(endpoint, naturalKey)
+		}
+		if err != nil {
+			return err
+		}
+
+		return m.mapping.record(endpoint, naturalKey, auditObjectID(endpoint, v), mirrorID)
+	case "PUT", "PATCH":
+		mirrorID, err := c.translate(endpoint, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := m.client.doRequest(r.method, fmt.Sprintf("%v/%v", endpoint, mirrorID), "", translated, nil); err != nil {
+			return err
+		}
+
+		// a renamed object is kept under its new natural key
+		naturalKey := mirrorNaturalKey(endpoint, r.body)
+		if r.method == "PATCH" {
+			naturalKey = patchedNaturalKey(endpoint, r.body)
+		}
+		if naturalKey != "" {
+			return m.mapping.record(endpoint, naturalKey, id, mirrorID)
+		}
+		return nil
+	case "DELETE":
+		if r.err != nil {
+			return r.err
+		}
+
+		resp, err := m.client.doRequest("DELETE", fmt.Sprintf("%v/%v", endpoint, r.mirrorID), "", nil, nil)
+		if err != nil && !isNotFound(resp, err) {
+			return err
+		}
+
+		return m.mapping.forget(endpoint, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mirrorServer knows the user "jane" with id and records all mutating requests.
+// Updates are answered with 200 and the user, like AWS does.
+func mirrorServer(id string) (*[]string, http.HandlerFunc) {
+	var mu sync.Mutex
+	requests := []string{}
+	deleted := false
+
+	return &requests, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && deleted:
+			w.WriteHeader(404)
+		case r.Method == "GET" && r.URL.Path == "/Users/"+id:
+			fmt.Fprintf(w, `{"id":%q,"userName":"jane"}`, id)
+		case r.Method == "GET" && r.URL.Path == "/Users":
+			fmt.Fprintf(w, `{"totalResults":1,"Resources":[{"id":%q,"userName":"jane"}]}`, id)
+		default:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			if r.Method == "DELETE" {
+				deleted = true
+				w.WriteHeader(204)
+				return
+			}
+			fmt.Fprintf(w, `{"id":%q,"userName":"jane"}`, id)
+		}
+	}
+}
+
+func newMirrorTestClient(t *testing.T, mode string, primary http.HandlerFunc, mirror http.HandlerFunc) *APIClient {
+	t.Helper()
+
+	c := newTestClient(t, primary)
+	m, err := NewMirror(newTestClient(t, mirror), mode, filepath.Join(t.TempDir(), "mapping.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Mirror = m
+
+	return c
+}
+
+func TestMirrorDeletesUnmappedObjects(t *testing.T) {
+	_, primary := mirrorServer("1")
+	mirrorRequests, mirror := mirrorServer("m-1")
+	c := newMirrorTestClient(t, MirrorModeFail, primary, mirror)
+
+	if _, err := c.DeleteUser("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if diags := c.drainDiagnostics(); len(diags) != 0 {
+		t.Errorf("expected the deletion to be mirrored, got %v", diags)
+	}
+	if strings.Join(*mirrorRequests, ",") != "DELETE /Users/m-1" {
+		t.Errorf("expected the user to be deleted on the mirror, got %v", *mirrorRequests)
+	}
+}
+
+func TestMirrorFailuresAreReportedByTheirOperation(t *testing.T) {
+	_, primary := mirrorServer("1")
+	c := newMirrorTestClient(t, MirrorModeFail, primary, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	})
+
+	failing := c.forOperation()
+	other := c.forOperation()
+
+	if _, err := failing.DeleteUser("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if diags := failing.drainDiagnostics(); !diags.HasError() || !strings.Contains(diags[0].Detail, "not on the mirror") {
+		t.Errorf("expected the failure to be reported by the deleting operation, got %v", diags)
+	}
+	if diags := append(other.drainDiagnostics(), c.drainDiagnostics()...); len(diags) != 0 {
+		t.Errorf("expected no diagnostics of other operations, got %v", diags)
+	}
+}
+
+func TestMirrorUpdatesAnsweredWithTheObject(t *testing.T) {
+	_, primary := mirrorServer("1")
+	mirrorRequests, mirror := mirrorServer("m-1")
+	c := newMirrorTestClient(t, MirrorModeFail, primary, mirror)
+
+	if _, _, err := c.PutUser(&User{UserName: "jane"}, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if diags := c.drainDiagnostics(); len(diags) != 0 {
+		t.Errorf("expected the update to be mirrored, got %v", diags)
+	}
+	if strings.Join(*mirrorRequests, ",") != "PUT /Users/m-1" {
+		t.Errorf("expected the user to be updated on the mirror, got %v", *mirrorRequests)
+	}
+}
+
+func TestMirrorPreflightFollowsMode(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	}))
+	t.Cleanup(server.Close)
+
+	get := func(k string) interface{} {
+		return map[string]interface{}{"endpoint": server.URL, "token": "rejected"}[k]
+	}
+
+	for _, mode := range mirrorModes {
+		cc := clientConfig{
+			settings:          &Settings{},
+			transportOptions:  TransportOptions{InsecureSkipVerify: true},
+			checkWarningsOnly: mode == MirrorModeWarn,
+		}
+
+		_, diags := cc.newClient("", get, false)
+		if len(diags) == 0 || diags.HasError() != (mode == MirrorModeFail) {
+			t.Errorf("%v: expected the rejected token to be reported as %v, got %v", mode, mode, diags)
+		}
+	}
+}
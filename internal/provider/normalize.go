@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizationRules are applied to user attributes before they are written. Differences they remove are not planned.
+type NormalizationRules struct {
+	TrimWhitespace        bool
+	UnicodeNFC            bool
+	LowercaseUserName     bool
+	TransliterateUserName bool
+	LowercaseEmailAddress bool
+}
+
+// letters which don't decompose into an ASCII letter and combining marks
+var transliterations = map[rune]string{
+	'ß': "ss", 'ẞ': "SS",
+	'æ': "ae", 'Æ': "AE",
+	'œ': "oe", 'Œ': "OE",
+	'ø': "o", 'Ø': "O",
+	'ł': "l", 'Ł': "L",
+	'đ': "d", 'Đ': "D",
+	'ð': "d", 'Ð': "D",
+	'þ': "th", 'Þ': "TH",
+	'ı': "i",
+}
+
+// transliterate replaces letters with diacritics by their base letter, e.g. "é" by "e", and
+// other non-ASCII letters by their usual ASCII spelling, e.g. "ß" by "ss".
+func transliterate(s string) string {
+	var b strings.Builder
+
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// drop combining marks left over by the decomposition
+		case r <= unicode.MaxASCII:
+			b.WriteRune(r)
+		default:
+			if t, ok := transliterations[r]; ok {
+				b.WriteString(t)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// normalize applies the rules shared by all attributes.
+func (n NormalizationRules) normalize(s string) string {
+	if n.TrimWhitespace {
+		s = strings.TrimSpace(s)
+	}
+	if n.UnicodeNFC {
+		s = norm.NFC.String(s)
+	}
+	return s
+}
+
+func (n NormalizationRules) NormalizeUserName(s string) string {
+	s = n.normalize(s)
+	if n.TransliterateUserName {
+		s = transliterate(s)
+	}
+	if n.LowercaseUserName {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+func (n NormalizationRules) NormalizeEmailAddress(s string) string {
+	s = n.normalize(s)
+	if n.LowercaseEmailAddress {
+		s = strings.ToLower(s)
+	}
+	return s
+}
+
+func (n NormalizationRules) NormalizeName(s string) string {
+	return n.normalize(s)
+}
+
+// NormalizeUser applies the rules to all normalized attributes of user.
+func (n NormalizationRules) NormalizeUser(user *User) {
+	user.UserName = n.NormalizeUserName(user.UserName)
+	user.DisplayName = n.NormalizeName(user.DisplayName)
+	user.Name.GivenName = n.NormalizeName(user.Name.GivenName)
+	user.Name.FamilyName = n.NormalizeName(user.Name.FamilyName)
+
+	for i := range user.Emails {
+		user.Emails[i].Value = n.NormalizeEmailAddress(user.Emails[i].Value)
+	}
+}
+
+// userAttributeNormalizers maps the attributes of the user resource to their normalization.
+func (n NormalizationRules) userAttributeNormalizers() map[string]func(string) string {
+	return map[string]func(string) string{
+		"user_name":     n.NormalizeUserName,
+		"display_name":  n.NormalizeName,
+		"given_name":    n.NormalizeName,
+		"family_name":   n.NormalizeName,
+		"email_address": n.NormalizeEmailAddress,
+	}
+}
+
+// withNormalizedAttributes suppresses diffs of the user attributes whose old and new value normalize to the same value,
+// so e.g. a mixed-case user_name doesn't differ from the lowercase one read back. The rules are read from the
+// configured provider, because diff suppression has no access to it otherwise.
+func withNormalizedAttributes(p *schema.Provider, name string, r *schema.Resource) {
+	if name != "aws-sso-scim_user" {
+		return
+	}
+
+	for k := range (NormalizationRules{}).userAttributeNormalizers() {
+		attribute := k
+		r.Schema[attribute].DiffSuppressFunc = func(_, old, new string, d *schema.ResourceData) bool {
+			client, ok := p.Meta().(*APIClient)
+			if !ok || d.Id() == "" {
+				return false
+			}
+
+			normalize := client.Normalization.userAttributeNormalizers()[attribute]
+			return normalize(old) == normalize(new)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Users and groups are listed in pages of this size
+const listPageSize = 100
+
+// isOwnedEndpoint tells whether objects of the SCIM endpoint are stamped with the ownership prefix.
+func isOwnedEndpoint(endpoint string) bool {
+	return endpoint == "Users" || endpoint == "Groups"
+}
+
+// stampExternalID prefixes externalID with the ownership prefix, so the object is recognized as
+// created by this configuration. Without an externalID, the prefix alone is used.
+func (c *APIClient) stampExternalID(externalID string) string {
+	if c.OwnershipPrefix == "" || strings.HasPrefix(externalID, c.OwnershipPrefix) {
+		return externalID
+	}
+	return c.OwnershipPrefix + externalID
+}
+
+// unstampExternalID removes the ownership prefix from externalID.
+func (c *APIClient) unstampExternalID(externalID string) string {
+	if c.OwnershipPrefix == "" {
+		return externalID
+	}
+	return strings.TrimPrefix(externalID, c.OwnershipPrefix)
+}
+
+// owns tells whether externalID carries the ownership prefix.
+func (c *APIClient) owns(externalID string) bool {
+	return c.OwnershipPrefix == "" || strings.HasPrefix(externalID, c.OwnershipPrefix)
+}
+
+// refuseUnowned returns an error if the user or group at endpoint with id was not created by this
+// configuration, unless allow_unowned_changes is enabled. Objects which are gone are not refused.
+func (c *APIClient) refuseUnowned(action string, endpoint string, id string) error {
+	if c.OwnershipPrefix == "" || c.AllowUnownedChanges {
+		return nil
+	}
+
+	if !isOwnedEndpoint(endpoint) {
+		return nil
+	}
+	kind := strings.ToLower(strings.TrimSuffix(endpoint, "s"))
+
+	var current struct {
+		ExternalID string `json:"externalId"`
+	}
+	resp, err := c.doRequest("GET", fmt.Sprintf("%v/%v", endpoint, id), "", nil, &current)
+	if isNotFound(resp, err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to check whether %v %v is owned by this configuration: %v", kind, id, err)
+	}
+
+	if !c.owns(current.ExternalID) {
+		return fmt.Errorf("%v %v is not owned by this configuration, its externalId %q does not start with ownership_prefix %q. Refusing to %v, set allow_unowned_changes to override", kind, id, current.ExternalID, c.OwnershipPrefix, action)
+	}
+
+	return nil
+}
+
+// stampBulkOperations returns a copy of ops, in which users and groups created by POST operations
+// are stamped with the ownership prefix, like those created by CreateUser and CreateGroup.
+func (c *APIClient) stampBulkOperations(ops []BulkOperation) ([]BulkOperation, error) {
+	if c.OwnershipPrefix == "" {
+		return ops, nil
+	}
+
+	stamped := make([]BulkOperation, 0, len(ops))
+	for _, op := range ops {
+		if strings.ToUpper(op.Method) == "POST" && isOwnedEndpoint(strings.Trim(op.Path, "/")) {
+			encoded, err := json.Marshal(op.Data)
+			if err != nil {
+				return nil, err
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal(encoded, &data); err != nil {
+				return nil, fmt.Errorf("invalid data of %v %v: %v", op.Method, op.Path, err)
+			}
+			if data == nil {
+				data = map[string]interface{}{}
+			}
+
+			externalID, _ := data["externalId"].(string)
+			data["externalId"] = c.stampExternalID(externalID)
+			op.Data = data
+		}
+		stamped = append(stamped, op)
+	}
+
+	return stamped, nil
+}
+
+// refuseAdoption returns an error if an existing user or group found after an ambiguous create was not
+// created by this configuration, so it is not taken over, unless allow_unowned_changes is enabled.
+func (c *APIClient) refuseAdoption(kind string, id string, name string, externalID string) error {
+	if c.AllowUnownedChanges || c.owns(externalID) {
+		return nil
+	}
+	return fmt.Errorf("%v %q exists already as %v, but is not owned by this configuration, its externalId %q does not start with ownership_prefix %q. Refusing to adopt it, set allow_unowned_changes to override", kind, name, id, externalID, c.OwnershipPrefix)
+}
+
+func (c *APIClient) ListAllUsers() ([]User, error) {
+	users := []User{}
+
+	for {
+		var userLR UserListResponse
+		_, err := c.doRequest("GET", fmt.Sprintf("Users?startIndex=%v&count=%v", len(users)+1, listPageSize), "", nil, &userLR)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, userLR.Resources...)
+
+		if len(userLR.Resources) == 0 || len(users) >= userLR.TotalResults {
+			return users, nil
+		}
+	}
+}
+
+func (c *APIClient) ListAllGroups() ([]Group, error) {
+	groups := []Group{}
+
+	for {
+		var groupLR GroupListResponse
+		_, err := c.doRequest("GET", fmt.Sprintf("Groups?startIndex=%v&count=%v", len(groups)+1, listPageSize), "", nil, &groupLR)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, groupLR.Resources...)
+
+		if len(groupLR.Resources) == 0 || len(groups) >= groupLR.TotalResults {
+			return groups, nil
+		}
+	}
+}
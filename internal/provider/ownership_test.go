@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// conflictServer refuses to create users and groups, because they exist already with externalID.
+func conflictServer(externalID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "POST" {
+			w.WriteHeader(409)
+			return
+		}
+		fmt.Fprintf(w, `{"totalResults":1,"Resources":[{"id":"existing","userName":"jdoe","displayName":"g","externalId":%q}]}`, externalID)
+	}
+}
+
+func TestCreateAdoptsOnlyOwnedObjects(t *testing.T) {
+	cases := []struct {
+		name       string
+		externalID string
+		allow      bool
+		adopt      bool
+	}{
+		{name: "owned", externalID: "terraform:", adopt: true},
+		{name: "owned with externalId", externalID: "terraform:1234", adopt: true},
+		{name: "owned by the IdP", externalID: "idp-1234"},
+		{name: "without externalId", externalID: ""},
+		{name: "unowned changes allowed", externalID: "idp-1234", allow: true, adopt: true},
+	}
+
+	for _, tc := range cases {
+		c := newTestClient(t, conflictServer(tc.externalID))
+		c.OwnershipPrefix = "terraform:"
+		c.AllowUnownedChanges = tc.allow
+
+		user, _, userErr := c.CreateUser(&User{UserName: "jdoe"})
+		group, _, groupErr := c.CreateGroup(&Group{DisplayName: "g"})
+
+		if tc.adopt {
+			if userErr != nil || user.ID != "existing" || groupErr != nil || group.ID != "existing" {
+				t.Errorf("%v: expected existing objects to be adopted, got %v and %v", tc.name, userErr, groupErr)
+			}
+			continue
+		}
+
+		if userErr == nil || !strings.Contains(userErr.Error(), "Refusing to adopt it") {
+			t.Errorf("%v: expected user not to be adopted, got %v", tc.name, userErr)
+		}
+		if groupErr == nil || !strings.Contains(groupErr.Error(), "Refusing to adopt it") {
+			t.Errorf("%v: expected group not to be adopted, got %v", tc.name, groupErr)
+		}
+	}
+}
+
+func TestBulkCreatesAreStamped(t *testing.T) {
+	ops := []BulkOperation{
+		{Method: "POST", BulkID: "u", Path: "/Users", Data: &User{UserName: "jdoe"}},
+		{Method: "POST", BulkID: "g", Path: "/Groups", Data: map[string]interface{}{"displayName": "g", "externalId": "1234"}},
+		{Method: "POST", BulkID: "d", Path: "/Devices", Data: map[string]interface{}{"displayName": "d"}},
+	}
+
+	for _, bulkSupported := range []bool{true, false} {
+		var mu sync.Mutex
+		externalIDs := map[string]interface{}{}
+
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			var created []map[string]interface{}
+			switch r.URL.Path {
+			case "/ServiceProviderConfig":
+				fmt.Fprintf(w, `{"bulk":{"supported":%v}}`, bulkSupported)
+				return
+			case "/Bulk":
+				var req struct {
+					Operations []struct {
+						Path string                 `json:"path"`
+						Data map[string]interface{} `json:"data"`
+					}
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				for _, op := range req.Operations {
+					op.Data["path"] = op.Path
+					created = append(created, op.Data)
+				}
+				fmt.Fprint(w, `{"Operations":[]}`)
+			default:
+				var data map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&data)
+				data["path"] = r.URL.Path
+				created = append(created, data)
+				w.WriteHeader(201)
+				fmt.Fprint(w, `{"id":"1"}`)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, data := range created {
+				externalIDs[data["path"].(string)] = data["externalId"]
+			}
+		})
+		c.OwnershipPrefix = "terraform:"
+
+		if _, err := c.Bulk(ops, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]interface{}{"/Users": "terraform:", "/Groups": "terraform:1234", "/Devices": nil}
+		if !reflect.DeepEqual(externalIDs, expected) {
+			t.Errorf("bulk supported %v: expected externalIds %v, got %v", bulkSupported, expected, externalIDs)
+		}
+	}
+
+	// the operations of the caller are left untouched
+	if ops[1].Data.(map[string]interface{})["externalId"] != "1234" {
+		t.Errorf("expected the given operations not to be changed, got %v", ops[1].Data)
+	}
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Terraform reads the schema before the provider is configured, so whether attributes are sensitive
+// can only be decided by this environment variable, and not by a provider argument. It also redacts
+// the natural keys of users in the audit log and events.
+const SensitivePIIEnv = "AWS_SSO_SCIM_SENSITIVE_PII"
+
+// attributes holding personal data, by resource or data source
+var piiAttributes = map[string][]string{
+	"aws-sso-scim_user":     {"given_name", "family_name", "display_name", "email_address"},
+	"aws-sso-scim_resource": {"attributes"},
+}
+
+var piiDataSourceAttributes = map[string][]string{
+	"aws-sso-scim_user":    {"given_name", "family_name", "display_name", "email_address"},
+	"aws-sso-scim_request": {"response"},
+}
+
+// SCIM user attributes holding personal data, in lower case
+var piiKeys = map[string]bool{
+	"name":         true,
+	"displayname":  true,
+	"nickname":     true,
+	"emails":       true,
+	"phonenumbers": true,
+	"addresses":    true,
+}
+
+func sensitivePIIFromEnv() bool {
+	sensitive, _ := strconv.ParseBool(os.Getenv(SensitivePIIEnv))
+	return sensitive
+}
+
+// withSensitivePII marks the attributes of r holding personal data as sensitive.
+func withSensitivePII(r *schema.Resource, attributes []string) {
+	for _, k := range attributes {
+		if s, ok := r.Schema[k]; ok {
+			s.Sensitive = true
+		}
+	}
+}
+
+// redactPII returns body with the personal data of users replaced, e.g. for logging a request to path.
+// The data of each operation of a bulk request is redacted by its own path. Bodies of requests to
+// other endpoints are returned as they are.
+func redactPII(path string, body interface{}) interface{} {
+	path = strings.TrimPrefix(path, "/")
+	isBulk := path == "Bulk"
+	if body == nil || !strings.HasPrefix(path, "Users") && !isBulk {
+		return body
+	}
+
+	// work on a generic copy of body
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil
+	}
+	var generic interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil
+	}
+
+	if !isBulk {
+		return redactValue(generic)
+	}
+
+	bulkReq, _ := generic.(map[string]interface{})
+	operations, _ := bulkReq["Operations"].([]interface{})
+	for _, v := range operations {
+		if op, ok := v.(map[string]interface{}); ok {
+			opPath, _ := op["path"].(string)
+			if data, ok := op["data"]; ok {
+				op["data"] = redactPII(opPath, data)
+			}
+		}
+	}
+	return generic
+}
+
+func redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		// PATCH operations name the attribute in their path
+		if path, ok := value["path"].(string); ok && isPIIPath(path) {
+			if _, ok := value["value"]; ok {
+				value["value"] = "<redacted>"
+			}
+		}
+		for k, child := range value {
+			if piiKeys[strings.ToLower(k)] {
+				value[k] = "<redacted>"
+			} else {
+				value[k] = redactValue(child)
+			}
+		}
+		return value
+	case []interface{}:
+		for i, child := range value {
+			value[i] = redactValue(child)
+		}
+		return value
+	}
+	return v
+}
+
+// isPIIPath tells whether a PATCH path like "name.givenName" or `emails[type eq "work"].value` refers to personal data.
+func isPIIPath(path string) bool {
+	attribute := strings.ToLower(path)
+	if i := strings.IndexAny(attribute, ".["); i >= 0 {
+		attribute = attribute[:i]
+	}
+	return piiKeys[attribute]
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRedactPII(t *testing.T) {
+	cases := []struct {
+		path     string
+		body     interface{}
+		redacted []string
+		kept     []string
+	}{
+		{
+			path:     "Users",
+			body:     &User{UserName: "jdoe", DisplayName: "Jane Doe", Name: Name{GivenName: "Jane"}, Emails: []Email{{Value: "jane@example.com"}}},
+			redacted: []string{"Jane Doe", "Jane", "jane@example.com"},
+			kept:     []string{"jdoe"},
+		},
+		{
+			path: "Users/1234",
+			body: OperationMessage{Operations: []Operation{
+				{Operation: "replace", Path: "name.givenName", Value: "Janet"},
+				{Operation: "replace", Path: `emails[type eq "work"].value`, Value: "janet@example.com"},
+				{Operation: "replace", Path: "active", Value: false},
+			}},
+			redacted: []string{"Janet", "janet@example.com"},
+			kept:     []string{"active"},
+		},
+		{
+			path: "Groups",
+			body: &Group{DisplayName: "Jane Doe's team"},
+			kept: []string{"Jane Doe's team"},
+		},
+		{
+			path: "Bulk",
+			body: BulkRequest{Operations: []BulkOperation{
+				{Method: "POST", Path: "/Users", Data: &User{UserName: "jdoe", Name: Name{FamilyName: "Doe"}, Emails: []Email{{Value: "jane@example.com"}}}},
+				{Method: "PATCH", Path: "/Users/1234", Data: OperationMessage{Operations: []Operation{{Operation: "replace", Path: "displayName", Value: "Janet Doe"}}}},
+				{Method: "POST", Path: "/Groups", Data: &Group{DisplayName: "Jane Doe's team"}},
+			}},
+			redacted: []string{"Doe\"", "jane@example.com", "Janet Doe"},
+			kept:     []string{"jdoe", "Jane Doe's team"},
+		},
+	}
+
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		// request bodies are redacted in logs even without SensitivePIIEnv
+		(&APIClient{Settings: &Settings{}}).logRequest("POST", tc.path, tc.body)
+		log.SetOutput(os.Stderr)
+
+		logged := buf.String()
+		for _, s := range tc.redacted {
+			if strings.Contains(logged, s) {
+				t.Errorf("%v: expected %q to be redacted, got %v", tc.path, s, logged)
+			}
+		}
+		for _, s := range tc.kept {
+			if !strings.Contains(logged, s) {
+				t.Errorf("%v: expected %q to be kept, got %v", tc.path, s, logged)
+			}
+		}
+	}
+}
+
+func TestAuditNaturalKey(t *testing.T) {
+	user := &User{UserName: "jdoe"}
+	group := &Group{DisplayName: "admins"}
+
+	c := &APIClient{Settings: &Settings{}}
+	if key := c.auditNaturalKey("Users", user); key != "jdoe" {
+		t.Errorf("expected user name, got %q", key)
+	}
+
+	c.SensitivePII = true
+	if key := c.auditNaturalKey("Users", user); key != "<redacted>" {
+		t.Errorf("expected user name to be redacted, got %q", key)
+	}
+	if key := c.auditNaturalKey("Groups", group); key != "admins" {
+		t.Errorf("expected group name to be kept, got %q", key)
+	}
+}
@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// PolicyRules are conventions for names and email addresses which are enforced when planning.
+// Nil patterns and empty domain lists are not enforced.
+type PolicyRules struct {
+	UserNamePattern         *regexp.Regexp
+	UserDisplayNamePattern  *regexp.Regexp
+	EmailDomains            []string
+	GroupDisplayNamePattern *regexp.Regexp
+}
+
+// checkPattern fails if the planned value of attribute, as normalized by normalize, does not match the pattern of rule.
+func checkPattern(d *schema.ResourceDiff, attribute string, rule string, pattern *regexp.Regexp, normalize func(string) string) error {
+	if pattern == nil || !d.NewValueKnown(attribute) {
+		return nil
+	}
+
+	value := normalize(d.Get(attribute).(string))
+	if !pattern.MatchString(value) {
+		return fmt.Errorf("%v %q violates policy rule %v: it does not match %q", attribute, value, rule, pattern.String())
+	}
+
+	return nil
+}
+
+// checkUserPolicy fails if the planned user violates any rule.
+func (p PolicyRules) checkUserPolicy(d *schema.ResourceDiff, n NormalizationRules) error {
+	if err := checkPattern(d, "user_name", "user_name_pattern", p.UserNamePattern, n.NormalizeUserName); err != nil {
+		return err
+	}
+	if err := checkPattern(d, "display_name", "user_display_name_pattern", p.UserDisplayNamePattern, n.NormalizeName); err != nil {
+		return err
+	}
+
+	if len(p.EmailDomains) == 0 || !d.NewValueKnown("email_address") {
+		return nil
+	}
+
+	email := n.NormalizeEmailAddress(d.Get("email_address").(string))
+	if email == "" {
+		return nil
+	}
+
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for _, allowed := range p.EmailDomains {
+		if strings.EqualFold(domain, allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("email_address %q violates policy rule email_domains: %q is not one of %v", email, domain, strings.Join(p.EmailDomains, ", "))
+}
+
+// checkGroupPolicy fails if the planned group violates any rule.
+func (p PolicyRules) checkGroupPolicy(d *schema.ResourceDiff) error {
+	return checkPattern(d, "display_name", "group_display_name_pattern", p.GroupDisplayNamePattern, func(s string) string { return s })
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// preflight performs a single cheap authenticated request, so a wrong endpoint or token is reported
+// once by the provider instead of by every resource.
+func preflight(client *APIClient) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var userLR UserListResponse
+	resp, err := client.doRequest("GET", "Users?count=1", "", nil, &userLR)
+	if err == nil {
+		return diags
+	}
+
+	endpoint := client.BaseURL.String()
+
+	var summary, detail string
+	var dnsErr *net.DNSError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var urlErr *url.Error
+
+	switch {
+	case errors.As(err, &dnsErr):
+		summary = "Unable to resolve SCIM endpoint"
+		detail = fmt.Sprintf("The host of %v could not be resolved: %v. Please check endpoint or region.", endpoint, dnsErr)
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr):
+		summary = "Unable to verify TLS certificate of SCIM endpoint"
+		detail = fmt.Sprintf("%v. If you are behind a TLS inspecting proxy, configure its certificate authority via ca_bundle_file or ca_bundle.", err)
+	case errors.As(err, &urlErr):
+		summary = "Unable to connect to SCIM endpoint"
+		detail = fmt.Sprintf("%v. Please check endpoint and proxy settings.", err)
+	case resp != nil && resp.StatusCode == 401:
+		summary = "SCIM token rejected"
+		detail = fmt.Sprintf("%v rejected the token with 401 unauthorized. The token might be invalid, expired or revoked.", endpoint)
+	case resp != nil && resp.StatusCode == 403:
+		summary = "SCIM token not permitted"
+		detail = fmt.Sprintf("%v rejected the token with 403 forbidden. The token might belong to a different tenant.", endpoint)
+	case resp != nil && resp.StatusCode == 404:
+		summary = "SCIM endpoint not found"
+		detail = fmt.Sprintf("%v returned 404 not found. Please check the tenant ID in the endpoint.", endpoint)
+	default:
+		summary = "SCIM endpoint preflight failed"
+		detail = fmt.Sprintf("Listing users at %v failed: %v", endpoint, err)
+	}
+
+	diags = append(diags, diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  summary,
+		Detail:   detail + " Set skip_preflight to skip this check.",
+	})
+
+	return diags
+}
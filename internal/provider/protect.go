@@ -0,0 +1,321 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// ProtectedPrincipals are users and groups which are never deleted or deactivated and never lose memberships.
+// Patterns are matched against user names, group display names and IDs.
+type ProtectedPrincipals struct {
+	UserNames  []string
+	GroupNames []string
+	IDs        []string
+	Patterns   []*regexp.Regexp
+}
+
+func (p ProtectedPrincipals) empty() bool {
+	return len(p.UserNames) == 0 && len(p.GroupNames) == 0 && len(p.IDs) == 0 && len(p.Patterns) == 0
+}
+
+// protects returns the rule protecting the principal with id and any of names, if there is one.
+func (p ProtectedPrincipals) protects(id string, nameRule string, protectedNames []string, names ...string) (string, bool) {
+	for _, protected := range p.IDs {
+		if id != "" && id == protected {
+			return fmt.Sprintf("ID %q", protected), true
+		}
+	}
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		for _, protected := range protectedNames {
+			if name == protected {
+				return fmt.Sprintf("%v %q", nameRule, protected), true
+			}
+		}
+	}
+
+	for _, pattern := range p.Patterns {
+		for _, v := range append([]string{id}, names...) {
+			if v != "" && pattern.MatchString(v) {
+				return fmt.Sprintf("pattern %q", pattern.String()), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func (p ProtectedPrincipals) protectsUser(id string, userNames ...string) (string, bool) {
+	return p.protects(id, "user name", p.UserNames, userNames...)
+}
+
+func (p ProtectedPrincipals) protectsGroup(id string, displayNames ...string) (string, bool) {
+	return p.protects(id, "group name", p.GroupNames, displayNames...)
+}
+
+// refuseProtectedUser returns an error if the user with id is protected. Its current user name is read
+// from the server if names are protected. If update is given, only its deactivation is refused.
+func (c *APIClient) refuseProtectedUser(action string, id string, update *User) error {
+	if c.Protected.empty() {
+		return nil
+	}
+	if update != nil && update.Active {
+		return nil
+	}
+
+	names := []string{}
+	if update != nil {
+		names = append(names, update.UserName)
+	}
+
+	if len(c.Protected.UserNames) > 0 || len(c.Protected.Patterns) > 0 {
+		var current User
+		resp, err := c.doRequest("GET", fmt.Sprintf("Users/%v", id), "", nil, &current)
+		switch {
+		case isNotFound(resp, err):
+		case err != nil:
+			return fmt.Errorf("unable to check whether user %v is protected: %v", id, err)
+		case update != nil && !current.Active:
+			// the user is inactive already
+			return nil
+		default:
+			names = append(names, current.UserName)
+		}
+	}
+
+	if rule, ok := c.Protected.protectsUser(id, names...); ok {
+		return fmt.Errorf("user %v is protected by %v, refusing to %v", id, rule, action)
+	}
+
+	return nil
+}
+
+// refuseProtectedGroup returns an error if the group with id is protected. Its current display name is read
+// from the server if names are protected.
+func (c *APIClient) refuseProtectedGroup(action string, id string) error {
+	if c.Protected.empty() {
+		return nil
+	}
+
+	names := []string{}
+
+	if len(c.Protected.GroupNames) > 0 || len(c.Protected.Patterns) > 0 {
+		var current Group
+		resp, err := c.doRequest("GET", fmt.Sprintf("Groups/%v", id), "", nil, &current)
+		switch {
+		case isNotFound(resp, err):
+		case err != nil:
+			return fmt.Errorf("unable to check whether group %v is protected: %v", id, err)
+		default:
+			names = append(names, current.DisplayName)
+		}
+	}
+
+	if rule, ok := c.Protected.protectsGroup(id, names...); ok {
+		return fmt.Errorf("group %v is protected by %v, refusing to %v", id, rule, action)
+	}
+
+	return nil
+}
+
+// refuseProtectedMembership returns an error if the user or the group of a membership to be removed is protected.
+func (c *APIClient) refuseProtectedMembership(group_id string, user_id string) error {
+	if err := c.refuseProtectedGroup(fmt.Sprintf("remove user %v from it", user_id), group_id); err != nil {
+		return err
+	}
+	return c.refuseProtectedUser(fmt.Sprintf("remove it from group %v", group_id), user_id, nil)
+}
+
+// refuseProtectedPatch returns an error if opmsg deactivates a protected user or removes members of a protected group.
+func (c *APIClient) refuseProtectedPatch(endpoint string, opmsg *OperationMessage, id string) error {
+	if c.Protected.empty() || opmsg == nil {
+		return nil
+	}
+
+	for _, op := range opmsg.Operations {
+		operation := strings.ToLower(op.Operation)
+		path := strings.ToLower(op.Path)
+
+		switch endpoint {
+		case "Users":
+			active, ok := op.Value.(bool)
+			if values, isObject := op.Value.(map[string]interface{}); isObject && path == "" {
+				active, ok = values["active"].(bool)
+			}
+			deactivates := (operation == "replace" || operation == "add") && (path == "active" || path == "") && ok && !active
+			if deactivates || (operation == "remove" && path == "active") {
+				return c.refuseProtectedUser("deactivate it", id, &User{})
+			}
+		case "Groups":
+			if strings.HasPrefix(path, "members") && (operation == "remove" || operation == "replace") {
+				if err := c.refuseProtectedGroup("remove members from it", id); err != nil {
+					return err
+				}
+				for _, member := range memberIDs(op.Value) {
+					if err := c.refuseProtectedUser(fmt.Sprintf("remove it from group %v", id), member, nil); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// refuseBulkOperation applies the checks of the client methods to a single bulk operation: protected principals,
+// ownership and deletion limits. Operations on objects created by the same bulk request are not checked.
+func (c *APIClient) refuseBulkOperation(op BulkOperation) error {
+	path := strings.TrimPrefix(op.Path, "/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[1] == "" || strings.Contains(path, bulkIDPrefix) {
+		return nil
+	}
+	endpoint, id := parts[0], parts[1]
+
+	encoded, err := json.Marshal(op.Data)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToUpper(op.Method) {
+	case "DELETE":
+		switch endpoint {
+		case "Users":
+			err = c.refuseProtectedUser("delete it", id, nil)
+		case "Groups":
+			err = c.refuseProtectedGroup("delete it", id)
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.refuseUnowned("delete it", endpoint, id); err != nil {
+			return err
+		}
+		return c.countDeletion(deletionKind(endpoint), id)
+	case "PATCH":
+		var opmsg OperationMessage
+		json.Unmarshal(encoded, &opmsg)
+		if err := c.refuseProtectedPatch(endpoint, &opmsg, id); err != nil {
+			return err
+		}
+		return c.refuseUnowned("update it", endpoint, id)
+	case "PUT":
+		if endpoint == "Users" {
+			var user User
+			json.Unmarshal(encoded, &user)
+			if err := c.refuseProtectedUser("deactivate it", id, &user); err != nil {
+				return err
+			}
+		}
+		return c.refuseUnowned("update it", endpoint, id)
+	}
+
+	return nil
+}
+
+// refuseBulkRequest returns an error if any operation of a request to the /Bulk endpoint is refused.
+func (c *APIClient) refuseBulkRequest(body interface{}) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	var bulkReq BulkRequest
+	if err := json.Unmarshal(encoded, &bulkReq); err != nil {
+		return fmt.Errorf("unable to check bulk request: %v", err)
+	}
+
+	for _, op := range bulkReq.Operations {
+		if err := c.refuseBulkOperation(op); err != nil {
+			return fmt.Errorf("refusing bulk request: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// memberIDs returns the user IDs of a members value like [{"value": "1234"}].
+func memberIDs(value interface{}) []string {
+	ids := []string{}
+
+	switch members := value.(type) {
+	case []map[string]string:
+		for _, m := range members {
+			ids = append(ids, m["value"])
+		}
+	case []interface{}:
+		for _, m := range members {
+			if member, ok := m.(map[string]interface{}); ok {
+				if id, ok := member["value"].(string); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	return ids
+}
+
+// withProtectedPrincipals extends the CustomizeDiff of r, so plans which replace or deactivate protected
+// principals fail. Plans which only destroy resources are not diffed, their deletion is refused by the client.
+func withProtectedPrincipals(name string, r *schema.Resource) {
+	customizeDiff := r.CustomizeDiff
+
+	r.CustomizeDiff = func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		if customizeDiff != nil {
+			if err := customizeDiff(ctx, d, meta); err != nil {
+				return err
+			}
+		}
+
+		provider, ok := meta.(*APIClient)
+		if !ok || provider.Protected.empty() || d.Id() == "" {
+			return nil
+		}
+
+		replaced := false
+		for k, s := range r.Schema {
+			if s.ForceNew && d.HasChange(k) {
+				replaced = true
+			}
+		}
+
+		// the object to be replaced lives in the old instance
+		instance, _ := d.GetChange("instance")
+		client, err := provider.Instance(instance.(string))
+		if err != nil {
+			return err
+		}
+
+		switch name {
+		case "aws-sso-scim_user":
+			if replaced {
+				return client.refuseProtectedUser("replace it", d.Id(), nil)
+			}
+			if d.HasChange("active") && d.NewValueKnown("active") && !d.Get("active").(bool) {
+				return client.refuseProtectedUser("deactivate it", d.Id(), &User{UserName: d.Get("user_name").(string)})
+			}
+		case "aws-sso-scim_group":
+			if replaced {
+				return client.refuseProtectedGroup("replace it", d.Id())
+			}
+		case "aws-sso-scim_group_member":
+			if replaced {
+				group_id, _ := d.GetChange("group_id")
+				user_id, _ := d.GetChange("user_id")
+				return client.refuseProtectedMembership(group_id.(string), user_id.(string))
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProtectedPrincipalsProtects(t *testing.T) {
+	p := ProtectedPrincipals{
+		UserNames:  []string{"breakglass"},
+		GroupNames: []string{"Admins"},
+		IDs:        []string{"1234"},
+		Patterns:   []*regexp.Regexp{regexp.MustCompile(`^svc-`)},
+	}
+
+	cases := []struct {
+		name         string
+		user         bool
+		id           string
+		names        []string
+		expectedRule string
+	}{
+		{name: "user by id", user: true, id: "1234", expectedRule: `ID "1234"`},
+		{name: "group by id", id: "1234", expectedRule: `ID "1234"`},
+		{name: "user by name", user: true, id: "1", names: []string{"breakglass"}, expectedRule: `user name "breakglass"`},
+		{name: "group by name", id: "1", names: []string{"Admins"}, expectedRule: `group name "Admins"`},
+		{name: "user names don't protect groups", id: "1", names: []string{"breakglass"}},
+		{name: "group names don't protect users", user: true, id: "1", names: []string{"Admins"}},
+		{name: "names are case sensitive", user: true, id: "1", names: []string{"BreakGlass"}},
+		{name: "pattern on name", user: true, id: "1", names: []string{"svc-deploy"}, expectedRule: `pattern "^svc-"`},
+		{name: "pattern on id", id: "svc-1", expectedRule: `pattern "^svc-"`},
+		{name: "any of the names", user: true, id: "1", names: []string{"jane", "breakglass"}, expectedRule: `user name "breakglass"`},
+		{name: "empty names are ignored", user: true, names: []string{""}},
+		{name: "unprotected", user: true, id: "1", names: []string{"jane"}},
+	}
+
+	for _, tc := range cases {
+		var rule string
+		var ok bool
+		if tc.user {
+			rule, ok = p.protectsUser(tc.id, tc.names...)
+		} else {
+			rule, ok = p.protectsGroup(tc.id, tc.names...)
+		}
+
+		if ok != (tc.expectedRule != "") || rule != tc.expectedRule {
+			t.Errorf("%v: expected rule %q, got %q (%v)", tc.name, tc.expectedRule, rule, ok)
+		}
+	}
+}
+
+func TestMemberIDs(t *testing.T) {
+	typed := memberIDs([]map[string]string{{"value": "1"}, {"value": "2"}})
+	generic := memberIDs([]interface{}{map[string]interface{}{"value": "3"}, "invalid"})
+
+	if strings.Join(typed, ",") != "1,2" || strings.Join(generic, ",") != "3" {
+		t.Errorf("unexpected member IDs %v and %v", typed, generic)
+	}
+}
+
+// protectedServer knows the user 1 named "breakglass" and records all other requests.
+func protectedServer(bulkSupported bool) (*[]string, http.HandlerFunc) {
+	var mu sync.Mutex
+	requests := []string{}
+
+	return &requests, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/ServiceProviderConfig":
+			fmt.Fprintf(w, `{"bulk":{"supported":%v}}`, bulkSupported)
+		case r.Method == "GET" && r.URL.Path == "/Users/1":
+			fmt.Fprint(w, `{"id":"1","userName":"breakglass","active":true}`)
+		case r.Method == "GET" && r.URL.Path == "/Users/2":
+			fmt.Fprint(w, `{"id":"2","userName":"jane","active":true}`)
+		default:
+			mu.Lock()
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(204)
+		}
+	}
+}
+
+func TestBulkRefusesProtectedPrincipals(t *testing.T) {
+	protected := ProtectedPrincipals{UserNames: []string{"breakglass"}}
+
+	ops := []BulkOperation{
+		{Method: "DELETE", Path: "/Users/2"},
+		{Method: "DELETE", Path: "/Users/1"},
+	}
+
+	t.Run("bulk endpoint", func(t *testing.T) {
+		requests, handler := protectedServer(true)
+		c := newTestClient(t, handler)
+		c.Protected = protected
+
+		if _, err := c.Bulk(ops, 0); err == nil || !strings.Contains(err.Error(), `protected by user name "breakglass"`) {
+			t.Errorf("expected bulk request to be refused, got %v", err)
+		}
+		if len(*requests) != 0 {
+			t.Errorf("expected nothing to be sent, got %v", *requests)
+		}
+	})
+
+	t.Run("sequential", func(t *testing.T) {
+		requests, handler := protectedServer(false)
+		c := newTestClient(t, handler)
+		c.Protected = protected
+
+		results, err := c.Bulk(ops, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0].Err != nil || results[1].Err == nil {
+			t.Errorf("expected only the deletion of the protected user to fail, got %v and %v", results[0].Err, results[1].Err)
+		}
+		if strings.Join(*requests, ",") != "DELETE /Users/2" {
+			t.Errorf("expected only the unprotected user to be deleted, got %v", *requests)
+		}
+	})
+
+	t.Run("deletion limits", func(t *testing.T) {
+		requests, handler := protectedServer(false)
+		c := newTestClient(t, handler)
+		c.DeletionLimits = DeletionLimits{Users: 1}
+
+		results, err := c.Bulk([]BulkOperation{
+			{Method: "DELETE", Path: "/Users/3"},
+			{Method: "DELETE", Path: "/Users/4"},
+		}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "max_deletions_per_run") {
+			t.Errorf("expected the second deletion to exceed the limit, got %v", results[1].Err)
+		}
+		if len(*requests) != 1 {
+			t.Errorf("expected one deletion to be sent, got %v", *requests)
+		}
+	})
+}
+
+func TestCreateResourceRefusesProtectedBulkOperations(t *testing.T) {
+	requests, handler := protectedServer(true)
+	c := newTestClient(t, handler)
+	c.Protected = ProtectedPrincipals{UserNames: []string{"breakglass"}}
+
+	// a raw request to the /Bulk endpoint, e.g. by the aws-sso-scim_resource resource
+	_, _, err := c.CreateResource("Bulk", map[string]interface{}{
+		"schemas": []string{bulkRequestSchema},
+		"Operations": []map[string]interface{}{
+			{"method": "PATCH", "path": "/Users/1", "data": map[string]interface{}{
+				"Operations": []map[string]interface{}{{"op": "replace", "path": "active", "value": false}},
+			}},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "refusing to deactivate it") {
+		t.Errorf("expected bulk request to be refused, got %v", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("expected nothing to be sent, got %v", *requests)
+	}
+}
@@ -2,9 +2,15 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 func init() {
@@ -27,32 +33,567 @@ func New(version string) func() *schema.Provider {
 	return func() *schema.Provider {
 		p := &schema.Provider{
 			DataSourcesMap: map[string]*schema.Resource{
-				"aws-sso-scim_user":  dataSourceUser(),
-				"aws-sso-scim_group": dataSourceGroup(),
+				"aws-sso-scim_user":      dataSourceUser(),
+				"aws-sso-scim_group":     dataSourceGroup(),
+				"aws-sso-scim_request":   dataSourceRequest(),
+				"aws-sso-scim_ownership": dataSourceOwnership(),
 			},
 			ResourcesMap: map[string]*schema.Resource{
 				"aws-sso-scim_user":         resourceUser(),
 				"aws-sso-scim_group":        resourceGroup(),
 				"aws-sso-scim_group_member": resourceGroupMember(),
+				"aws-sso-scim_resource":     resourceResource(),
+				"aws-sso-scim_patch":        resourcePatch(),
 			},
 			Schema: map[string]*schema.Schema{
 				"endpoint": {
 					Type:        schema.TypeString,
-					Description: "Full URL of your AWS SSO SCIM endpoint. Can also be provided via `AWS_SSO_SCIM_ENDPOINT` environment variable.",
-					Required:    true,
+					Description: "Full URL of your AWS SSO SCIM endpoint, e.g. `https://scim.eu-central-1.amazonaws.com/<tenant>/scim/v2/`. Either `endpoint` or `region` and `tenant_id` are required. Can also be provided via `AWS_SSO_SCIM_ENDPOINT` environment variable.",
+					Optional:    true,
 					DefaultFunc: schema.EnvDefaultFunc("AWS_SSO_SCIM_ENDPOINT", nil),
 				},
+				"region": {
+					Type:        schema.TypeString,
+					Description: "AWS region of your AWS SSO instance, used together with `tenant_id` to build the endpoint. Can also be provided via `AWS_SSO_SCIM_REGION` environment variable.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("AWS_SSO_SCIM_REGION", nil),
+				},
+				"tenant_id": {
+					Type:        schema.TypeString,
+					Description: "Tenant ID of your AWS SSO instance, the path segment before `/scim/v2/` of the endpoint. Can also be provided via `AWS_SSO_SCIM_TENANT_ID` environment variable.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("AWS_SSO_SCIM_TENANT_ID", nil),
+				},
 				"token": {
 					Type:        schema.TypeString,
-					Description: "Authentication token of your AWS SSO SCIM endpoint. Can also be provided via `AWS_SSO_SCIM_TOKEN` environment variable.",
-					Required:    true,
-					DefaultFunc: schema.EnvDefaultFunc("AWS_SSO_SCIM_TOKEN", nil),
+					Description: "Authentication token of your AWS SSO SCIM endpoint. Can also be provided via `AWS_SSO_SCIM_TOKEN` environment variable. " + tokenPrecedenceDescription,
+					Optional:    true,
+					Sensitive:   true,
+				},
+				"tokens": {
+					Type:        schema.TypeList,
+					Description: "Ordered list of authentication tokens. If a token is rejected, the request is retried with the next token, which is then used for all further requests. Helpful while rotating tokens.",
+					Optional:    true,
+					Sensitive:   true,
+					Elem: &schema.Schema{
+						Type: schema.TypeString,
+					},
+				},
+				"token_file": {
+					Type:        schema.TypeString,
+					Description: "Path to a file containing the authentication token. The file is read again whenever it changes. Can also be provided via `AWS_SSO_SCIM_TOKEN_FILE` environment variable.",
+					Optional:    true,
+				},
+				"token_command": {
+					Type:        schema.TypeString,
+					Description: "Command printing the authentication token, either as plain text or as JSON like `{\"token\": \"...\", \"expires_at\": \"2024-01-01T00:00:00Z\"}`. The command is run again once the token has expired. Can also be provided via `AWS_SSO_SCIM_TOKEN_COMMAND` environment variable.",
+					Optional:    true,
+				},
+				"token_expires_at": {
+					Type:         schema.TypeString,
+					Description:  "Expiry of the authentication token as RFC 3339 timestamp, e.g. `2025-06-30T12:00:00Z`. Overrides the expiry printed by `token_command`. Can also be provided via `AWS_SSO_SCIM_TOKEN_EXPIRES_AT` environment variable.",
+					Optional:     true,
+					DefaultFunc:  schema.EnvDefaultFunc("AWS_SSO_SCIM_TOKEN_EXPIRES_AT", nil),
+					ValidateFunc: validation.IsRFC3339Time,
+				},
+				"token_expiry_warning_days": {
+					Type:         schema.TypeInt,
+					Description:  fmt.Sprintf("Days before the expiry of the authentication token to start warning about it. Defaults to `%v`.", DefaultTokenExpiryWarningDays),
+					Optional:     true,
+					Default:      DefaultTokenExpiryWarningDays,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"http_proxy": {
+					Type:        schema.TypeString,
+					Description: "URL of the proxy to connect to the SCIM endpoint through, e.g. `http://proxy.example.com:3128`. Defaults to the `HTTPS_PROXY` environment variable.",
+					Optional:    true,
+				},
+				"no_proxy": {
+					Type:        schema.TypeString,
+					Description: "Comma separated list of hosts to connect to without proxy. Defaults to the `NO_PROXY` environment variable.",
+					Optional:    true,
+				},
+				"ca_bundle_file": {
+					Type:        schema.TypeString,
+					Description: "Path to a PEM file of additional certificate authorities to trust, e.g. of a TLS inspecting proxy.",
+					Optional:    true,
+				},
+				"ca_bundle": {
+					Type:        schema.TypeString,
+					Description: "PEM encoded additional certificate authorities to trust, e.g. of a TLS inspecting proxy.",
+					Optional:    true,
+				},
+				"client_cert": {
+					Type:         schema.TypeString,
+					Description:  "PEM encoded client certificate for mutual TLS.",
+					Optional:     true,
+					RequiredWith: []string{"client_key"},
+				},
+				"client_key": {
+					Type:         schema.TypeString,
+					Description:  "PEM encoded private key of the client certificate for mutual TLS.",
+					Optional:     true,
+					Sensitive:    true,
+					RequiredWith: []string{"client_cert"},
+				},
+				"insecure_skip_verify": {
+					Type:        schema.TypeBool,
+					Description: "Do not verify the TLS certificate of the SCIM endpoint. Only meant for local test servers. Defaults to `false`.",
+					Optional:    true,
+					Default:     false,
+				},
+				"user_defaults": {
+					Type:        schema.TypeList,
+					Description: "Default attributes of `aws-sso-scim_user` resources which don't configure them.",
+					Optional:    true,
+					MaxItems:    1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"email_type": {
+								Type:        schema.TypeString,
+								Description: "Usage type of email adresses, e.g. 'work'.",
+								Optional:    true,
+							},
+							"active": {
+								Type:        schema.TypeBool,
+								Description: "Set users to be active. Defaults to `false`.",
+								Optional:    true,
+								Default:     false,
+							},
+							"user_type": {
+								Type:        schema.TypeString,
+								Description: "Type of users, e.g. 'Employee'.",
+								Optional:    true,
+							},
+							"locale": {
+								Type:        schema.TypeString,
+								Description: "Locale of users, e.g. 'en-US'.",
+								Optional:    true,
+							},
+							"timezone": {
+								Type:        schema.TypeString,
+								Description: "Time zone of users, e.g. 'Europe/Berlin'.",
+								Optional:    true,
+							},
+							"preferred_language": {
+								Type:        schema.TypeString,
+								Description: "Preferred language of users, e.g. 'en'.",
+								Optional:    true,
+							},
+						},
+					},
+				},
+				"normalization": {
+					Type:        schema.TypeList,
+					Description: "Rules normalizing attributes of `aws-sso-scim_user` resources before they are written. Differences which are removed by these rules don't show up in plans.",
+					Optional:    true,
+					MaxItems:    1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"trim_whitespace": {
+								Type:        schema.TypeBool,
+								Description: "Remove leading and trailing whitespace from user name, names and email address. Defaults to `false`.",
+								Optional:    true,
+								Default:     false,
+							},
+							"unicode_nfc": {
+								Type:        schema.TypeBool,
+								Description: "Convert user name, names and email address to Unicode normalization form C. Defaults to `false`.",
+								Optional:    true,
+								Default:     false,
+							},
+							"lowercase_user_name": {
+								Type:        schema.TypeBool,
+								Description: "Convert user names to lower case. Defaults to `false`.",
+								Optional:    true,
+								Default:     false,
+							},
+							"transliterate_user_name": {
+								Type:        schema.TypeBool,
+								Description: "Replace non-ASCII letters in user names, e.g. 'é' by 'e' and 'ß' by 'ss'. Defaults to `false`.",
+								Optional:    true,
+								Default:     false,
+							},
+							"lowercase_email_address": {
+								Type:        schema.TypeBool,
+								Description: "Convert email addresses to lower case. Defaults to `false`.",
+								Optional:    true,
+								Default:     false,
+							},
+						},
+					},
+				},
+				"policy": {
+					Type:        schema.TypeList,
+					Description: "Conventions for users and groups which are checked when planning. Plans violating them fail with an error naming the rule.",
+					Optional:    true,
+					MaxItems:    1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"user_name_pattern": {
+								Type:         schema.TypeString,
+								Description:  "Regular expression user names have to match.",
+								Optional:     true,
+								ValidateFunc: validation.StringIsValidRegExp,
+							},
+							"user_display_name_pattern": {
+								Type:         schema.TypeString,
+								Description:  "Regular expression display names of users have to match.",
+								Optional:     true,
+								ValidateFunc: validation.StringIsValidRegExp,
+							},
+							"email_domains": {
+								Type:        schema.TypeList,
+								Description: "Domains email addresses of users have to belong to, e.g. `example.com`.",
+								Optional:    true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+							"group_display_name_pattern": {
+								Type:         schema.TypeString,
+								Description:  "Regular expression display names of groups have to match, e.g. `^aws-(prod|dev)-[a-z0-9-]+$`.",
+								Optional:     true,
+								ValidateFunc: validation.StringIsValidRegExp,
+							},
+						},
+					},
+				},
+				"audit_log_file": {
+					Type:        schema.TypeString,
+					Description: "Path to a file every create, update and delete request is appended to as a line of JSON, with method, path, object ID, natural key, the request body without personal data, status, duration and metadata of the Terraform run from environment variables. Can also be provided via `AWS_SSO_SCIM_AUDIT_LOG_FILE` environment variable.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("AWS_SSO_SCIM_AUDIT_LOG_FILE", nil),
+				},
+				"event_sink": {
+					Type:        schema.TypeList,
+					Description: "Destinations of events like `user.created` or `group.member.added`, which are emitted after each successful create, update or delete. Events are delivered in the background, so they neither delay nor fail the apply. Failed deliveries are retried and reported as warning. Events still waiting when the provider exits are delivered for up to 1.5 seconds, then dropped.",
+					Optional:    true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"type": {
+								Type:         schema.TypeString,
+								Description:  "One of `webhook`, `file` or `stdout`.",
+								Required:     true,
+								ValidateFunc: validation.StringInSlice([]string{"webhook", "file", "stdout"}, false),
+							},
+							"url": {
+								Type:         schema.TypeString,
+								Description:  "URL events are posted to, required for `webhook`.",
+								Optional:     true,
+								ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+							},
+							"hmac_secret": {
+								Type:        schema.TypeString,
+								Description: "Secret the `webhook` signs events with. The HMAC-SHA256 of the body is sent hex encoded in the `X-Signature-256` header, prefixed with `sha256=`.",
+								Optional:    true,
+								Sensitive:   true,
+							},
+							"path": {
+								Type:        schema.TypeString,
+								Description: "Path to a file events are appended to as lines of JSON, required for `file`.",
+								Optional:    true,
+							},
+						},
+					},
+				},
+				"event_delivery_attempts": {
+					Type:         schema.TypeInt,
+					Description:  fmt.Sprintf("Number of attempts to deliver an event to a sink. Defaults to `%v`.", DefaultEventDeliveryAttempts),
+					Optional:     true,
+					Default:      DefaultEventDeliveryAttempts,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
+				"read_only": {
+					Type:        schema.TypeBool,
+					Description: "Refuse to create, update or delete anything, e.g. for plans in pull requests. Can also be provided via `AWS_SSO_SCIM_READ_ONLY` environment variable. Defaults to `false`.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("AWS_SSO_SCIM_READ_ONLY", false),
+				},
+				"skip_preflight": {
+					Type:        schema.TypeBool,
+					Description: "Skip the check of endpoint and token when configuring the provider, e.g. for offline use. Can also be provided via `AWS_SSO_SCIM_SKIP_PREFLIGHT` environment variable. Defaults to `false`.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("AWS_SSO_SCIM_SKIP_PREFLIGHT", false),
+				},
+				"drift_policy": {
+					Type:         schema.TypeString,
+					Description:  "What happens when a user, group, group membership or other object has been deleted outside of Terraform: `recreate` removes it from the state, so it is created again, `warn` does the same and reports a warning, `fail` stops with an error. Defaults to `recreate`.",
+					Optional:     true,
+					Default:      DriftPolicyRecreate,
+					ValidateFunc: validation.StringInSlice(driftPolicies, false),
+				},
+				"consistency_timeout": {
+					Type:         schema.TypeInt,
+					Description:  fmt.Sprintf("Seconds to wait for created users, groups and group memberships to become visible, before a missing object is considered deleted. Set to `0` to disable waiting. Defaults to `%v`.", DefaultConsistencyTimeout),
+					Optional:     true,
+					Default:      DefaultConsistencyTimeout,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"max_deletions_per_run": {
+					Type:        schema.TypeList,
+					Description: fmt.Sprintf("Maximum number of deletions per run, counted across all instances. Further deletions fail once a limit is reached. Set the `%v` environment variable to `true` to override the limits.", DeletionLimitOverrideEnv),
+					Optional:    true,
+					MaxItems:    1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"users": {
+								Type:         schema.TypeInt,
+								Description:  "Maximum number of deleted users. Defaults to `0`, which means unlimited.",
+								Optional:     true,
+								Default:      0,
+								ValidateFunc: validation.IntAtLeast(0),
+							},
+							"groups": {
+								Type:         schema.TypeInt,
+								Description:  "Maximum number of deleted groups. Defaults to `0`, which means unlimited.",
+								Optional:     true,
+								Default:      0,
+								ValidateFunc: validation.IntAtLeast(0),
+							},
+							"group_members": {
+								Type:         schema.TypeInt,
+								Description:  "Maximum number of removed group memberships. Defaults to `0`, which means unlimited.",
+								Optional:     true,
+								Default:      0,
+								ValidateFunc: validation.IntAtLeast(0),
+							},
+						},
+					},
+				},
+				"protected": {
+					Type:        schema.TypeList,
+					Description: "Users and groups which are never deleted, deactivated or removed from groups. Plans replacing or deactivating them fail, and the requests are refused before they are sent.",
+					Optional:    true,
+					MaxItems:    1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"user_names": {
+								Type:        schema.TypeList,
+								Description: "User names of protected users.",
+								Optional:    true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+							"group_names": {
+								Type:        schema.TypeList,
+								Description: "Display names of protected groups.",
+								Optional:    true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+							"ids": {
+								Type:        schema.TypeList,
+								Description: "IDs of protected users and groups.",
+								Optional:    true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+							"patterns": {
+								Type:        schema.TypeList,
+								Description: "Regular expressions matched against user names, group display names and IDs.",
+								Optional:    true,
+								Elem: &schema.Schema{
+									Type:         schema.TypeString,
+									ValidateFunc: validation.StringIsValidRegExp,
+								},
+							},
+						},
+					},
+				},
+				"ownership_prefix": {
+					Type:        schema.TypeString,
+					Description: "Prefix stamped into the `externalId` of users and groups created by this configuration, e.g. `terraform:`. Users and groups without it are neither updated, deleted nor adopted when creating, unless `allow_unowned_changes` is enabled.",
+					Optional:    true,
+				},
+				"allow_unowned_changes": {
+					Type:        schema.TypeBool,
+					Description: "Update and delete users and groups whose `externalId` does not start with `ownership_prefix`. Defaults to `false`.",
+					Optional:    true,
+					Default:     false,
+				},
+				"freeze_windows": {
+					Type:        schema.TypeList,
+					Description: "Periods during which nothing may be created, updated or deleted. A window is either a fixed range from `start` to `end`, or starts at every time matching `schedule` and lasts for `duration`.",
+					Optional:    true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"name": {
+								Type:         schema.TypeString,
+								Description:  "Name of the window, shown when a change is refused.",
+								Required:     true,
+								ValidateFunc: validation.StringIsNotEmpty,
+							},
+							"start": {
+								Type:         schema.TypeString,
+								Description:  "Start of a fixed window as RFC 3339 timestamp, e.g. `2024-03-25T00:00:00+01:00`.",
+								Optional:     true,
+								ValidateFunc: validation.IsRFC3339Time,
+							},
+							"end": {
+								Type:         schema.TypeString,
+								Description:  "End of a fixed window as RFC 3339 timestamp.",
+								Optional:     true,
+								ValidateFunc: validation.IsRFC3339Time,
+							},
+							"schedule": {
+								Type:        schema.TypeString,
+								Description: "Start of a recurring window in crontab format with the fields minute, hour, day of month, month and day of week, e.g. `0 0 20 12 *` for December 20th.",
+								Optional:    true,
+							},
+							"duration": {
+								Type:        schema.TypeString,
+								Description: "Duration of a recurring window, e.g. `336h`. At most 31 days.",
+								Optional:    true,
+							},
+							"timezone": {
+								Type:        schema.TypeString,
+								Description: "Time zone of `schedule`, e.g. `Europe/Berlin`. Defaults to `UTC`.",
+								Optional:    true,
+								Default:     "UTC",
+							},
+						},
+					},
+				},
+				"freeze_override_reason": {
+					Type:        schema.TypeString,
+					Description: "Reason to make changes during a freeze window anyway, e.g. a break-glass ticket. It is logged, reported as warning and recorded in the `audit_log_file`. Can also be provided via `AWS_SSO_SCIM_FREEZE_OVERRIDE_REASON` environment variable.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("AWS_SSO_SCIM_FREEZE_OVERRIDE_REASON", nil),
+				},
+				"instances": {
+					Type:        schema.TypeList,
+					Description: "Additional AWS SSO instances, selected by the `instance` argument of resources and data sources. Each has its own endpoint and token, all other provider settings are shared. Resources and data sources without `instance` use the endpoint and token configured at the top level.",
+					Optional:    true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"name": {
+								Type:         schema.TypeString,
+								Description:  "Name of the instance, referenced by the `instance` argument.",
+								Required:     true,
+								ValidateFunc: validation.StringIsNotEmpty,
+							},
+							"endpoint": {
+								Type:        schema.TypeString,
+								Description: "Full URL of the SCIM endpoint of the instance. Either `endpoint` or `region` and `tenant_id` are required.",
+								Optional:    true,
+							},
+							"region": {
+								Type:        schema.TypeString,
+								Description: "AWS region of the instance, used together with `tenant_id` to build the endpoint.",
+								Optional:    true,
+							},
+							"tenant_id": {
+								Type:        schema.TypeString,
+								Description: "Tenant ID of the instance.",
+								Optional:    true,
+							},
+							"token": {
+								Type:        schema.TypeString,
+								Description: "Authentication token of the instance.",
+								Optional:    true,
+								Sensitive:   true,
+							},
+							"tokens": {
+								Type:        schema.TypeList,
+								Description: "Ordered list of authentication tokens of the instance.",
+								Optional:    true,
+								Sensitive:   true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+							"token_file": {
+								Type:        schema.TypeString,
+								Description: "Path to a file containing the authentication token of the instance.",
+								Optional:    true,
+							},
+							"token_command": {
+								Type:        schema.TypeString,
+								Description: "Command printing the authentication token of the instance.",
+								Optional:    true,
+							},
+							"token_expires_at": {
+								Type:         schema.TypeString,
+								Description:  "Expiry of the authentication token of the instance as RFC 3339 timestamp.",
+								Optional:     true,
+								ValidateFunc: validation.IsRFC3339Time,
+							},
+						},
+					},
+				},
+				"mirror": {
+					Type:        schema.TypeList,
+					Description: "Second SCIM endpoint every create, update and delete of the default instance is replayed on, e.g. while migrating to a new instance. IDs are translated through a mapping persisted in `mapping_file`. Requests to the `/Bulk` endpoint are not mirrored. Requests to the mirror are recorded in `audit_log_file` as well.",
+					Optional:    true,
+					MaxItems:    1,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"endpoint": {
+								Type:        schema.TypeString,
+								Description: "Full URL of the SCIM endpoint of the mirror.",
+								Required:    true,
+							},
+							"token": {
+								Type:        schema.TypeString,
+								Description: "Authentication token of the mirror.",
+								Optional:    true,
+								Sensitive:   true,
+							},
+							"token_file": {
+								Type:        schema.TypeString,
+								Description: "Path to a file containing the authentication token of the mirror.",
+								Optional:    true,
+							},
+							"token_command": {
+								Type:        schema.TypeString,
+								Description: "Command printing the authentication token of the mirror.",
+								Optional:    true,
+							},
+							"mapping_file": {
+								Type:        schema.TypeString,
+								Description: "Path to a JSON file mapping the IDs of users, groups and other objects to the IDs of their copies on the mirror, by `userName` of users and `displayName` of groups. Objects missing in it are looked up on the mirror by these natural keys.",
+								Required:    true,
+							},
+							"mode": {
+								Type:         schema.TypeString,
+								Description:  "How changes which could not be mirrored are reported by the resource which made them: `warn` reports a warning, `fail` an error. The change itself has been applied already in both cases, so with `fail` a resource whose creation could not be mirrored is marked as tainted and replaced by the next apply. Failed checks of the endpoint and token of the mirror when configuring the provider are reported the same way. Defaults to `warn`.",
+								Optional:     true,
+								Default:      MirrorModeWarn,
+								ValidateFunc: validation.StringInSlice(mirrorModes, false),
+							},
+						},
+					},
 				},
 			},
 		}
 
 		p.ConfigureContextFunc = configure(version, p)
 
+		// the schema is read before the provider is configured, see SensitivePIIEnv
+		sensitivePII := sensitivePIIFromEnv()
+
+		for name, r := range p.DataSourcesMap {
+			if sensitivePII {
+				withSensitivePII(r, piiDataSourceAttributes[name])
+			}
+			withInstanceArgument(r, false)
+			withClientDiagnostics(r)
+		}
+		for name, r := range p.ResourcesMap {
+			if sensitivePII {
+				withSensitivePII(r, piiAttributes[name])
+			}
+			withInstanceArgument(r, true)
+			withNormalizedAttributes(p, name, r)
+			withWriteGuard(name, r)
+			withProtectedPrincipals(name, r)
+			withClientDiagnostics(r)
+		}
+
 		return p
 	}
 }
@@ -61,15 +602,438 @@ func configure(version string, p *schema.Provider) func(context.Context, *schema
 	return func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
 		var diags diag.Diagnostics
 
-		endpoint := d.Get("endpoint").(string)
-		token := d.Get("token").(string)
 		userAgent := p.UserAgent("terraform-provider-aws-sso-scim", version)
 
-		apiClient, err := NewClient(endpoint, token, userAgent)
-		if err != nil {
-			return nil, diag.FromErr(err)
+		settings := &Settings{
+			ConsistencyTimeout:   time.Duration(d.Get("consistency_timeout").(int)) * time.Second,
+			ReadOnly:             d.Get("read_only").(bool),
+			DriftPolicy:          d.Get("drift_policy").(string),
+			SensitivePII:         sensitivePIIFromEnv(),
+			OwnershipPrefix:      d.Get("ownership_prefix").(string),
+			AllowUnownedChanges:  d.Get("allow_unowned_changes").(bool),
+			FreezeOverrideReason: d.Get("freeze_override_reason").(string),
+		}
+
+		if v, ok := d.GetOk("user_defaults.0"); ok {
+			defaults := v.(map[string]interface{})
+			settings.UserDefaults = UserDefaults{
+				EmailType:         defaults["email_type"].(string),
+				Active:            defaults["active"].(bool),
+				UserType:          defaults["user_type"].(string),
+				Locale:            defaults["locale"].(string),
+				Timezone:          defaults["timezone"].(string),
+				PreferredLanguage: defaults["preferred_language"].(string),
+			}
+		}
+
+		if v, ok := d.GetOk("normalization.0"); ok {
+			rules := v.(map[string]interface{})
+			settings.Normalization = NormalizationRules{
+				TrimWhitespace:        rules["trim_whitespace"].(bool),
+				UnicodeNFC:            rules["unicode_nfc"].(bool),
+				LowercaseUserName:     rules["lowercase_user_name"].(bool),
+				TransliterateUserName: rules["transliterate_user_name"].(bool),
+				LowercaseEmailAddress: rules["lowercase_email_address"].(bool),
+			}
+		}
+
+		if v, ok := d.GetOk("max_deletions_per_run.0"); ok {
+			limits := v.(map[string]interface{})
+			settings.DeletionLimits = DeletionLimits{
+				Users:        limits["users"].(int),
+				Groups:       limits["groups"].(int),
+				GroupMembers: limits["group_members"].(int),
+			}
+
+			if override, _ := strconv.ParseBool(os.Getenv(DeletionLimitOverrideEnv)); override {
+				settings.DeletionLimits = DeletionLimits{}
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Warning,
+					Summary:  "Deletion limits overridden",
+					Detail:   fmt.Sprintf("%v is set, so max_deletions_per_run is ignored.", DeletionLimitOverrideEnv),
+				})
+			}
+		}
+
+		if v, ok := d.GetOk("policy.0"); ok {
+			policy := v.(map[string]interface{})
+			settings.Policy = PolicyRules{
+				EmailDomains: expandStringList(policy["email_domains"].([]interface{})),
+			}
+			// patterns are already checked by validation.StringIsValidRegExp
+			if pattern := policy["user_name_pattern"].(string); pattern != "" {
+				settings.Policy.UserNamePattern = regexp.MustCompile(pattern)
+			}
+			if pattern := policy["user_display_name_pattern"].(string); pattern != "" {
+				settings.Policy.UserDisplayNamePattern = regexp.MustCompile(pattern)
+			}
+			if pattern := policy["group_display_name_pattern"].(string); pattern != "" {
+				settings.Policy.GroupDisplayNamePattern = regexp.MustCompile(pattern)
+			}
+		}
+
+		if v, ok := d.GetOk("protected.0"); ok {
+			protected := v.(map[string]interface{})
+			settings.Protected = ProtectedPrincipals{
+				UserNames:  expandStringList(protected["user_names"].([]interface{})),
+				GroupNames: expandStringList(protected["group_names"].([]interface{})),
+				IDs:        expandStringList(protected["ids"].([]interface{})),
+			}
+			for _, pattern := range expandStringList(protected["patterns"].([]interface{})) {
+				// already checked by validation.StringIsValidRegExp
+				settings.Protected.Patterns = append(settings.Protected.Patterns, regexp.MustCompile(pattern))
+			}
+		}
+
+		for _, v := range d.Get("freeze_windows").([]interface{}) {
+			window, err := expandFreezeWindow(v.(map[string]interface{}))
+			if err != nil {
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Error,
+					Summary:  "Invalid freeze window",
+					Detail:   err.Error(),
+				})
+				return nil, diags
+			}
+			settings.FreezeWindows = append(settings.FreezeWindows, window)
+		}
+
+		if path := d.Get("audit_log_file").(string); path != "" {
+			auditLog, err := NewAuditLog(path)
+			if err != nil {
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Error,
+					Summary:  "Invalid audit log configuration",
+					Detail:   err.Error(),
+				})
+				return nil, diags
+			}
+			settings.AuditLog = auditLog
+		}
+
+		for _, v := range d.Get("event_sink").([]interface{}) {
+			sink, err := expandEventSink(v.(map[string]interface{}))
+			if err != nil {
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Error,
+					Summary:  "Invalid event sink",
+					Detail:   err.Error(),
+				})
+				return nil, diags
+			}
+			settings.EventQueues = append(settings.EventQueues, NewEventQueue(sink, d.Get("event_delivery_attempts").(int)))
+		}
+
+		transportOptions := TransportOptions{
+			HTTPProxy:          d.Get("http_proxy").(string),
+			NoProxy:            d.Get("no_proxy").(string),
+			CABundleFile:       d.Get("ca_bundle_file").(string),
+			CABundle:           d.Get("ca_bundle").(string),
+			ClientCert:         d.Get("client_cert").(string),
+			ClientKey:          d.Get("client_key").(string),
+			InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+		}
+
+		// fail early, before any client is created
+		if _, err := NewTransport(transportOptions); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Invalid transport configuration",
+				Detail:   err.Error(),
+			})
+			return nil, diags
+		}
+
+		if transportOptions.InsecureSkipVerify {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "TLS certificate verification disabled",
+				Detail:   "insecure_skip_verify is enabled, so the identity of the SCIM endpoint is not verified. Only use this for local test servers.",
+			})
+		}
+
+		clientConfig := clientConfig{
+			settings:         settings,
+			transportOptions: transportOptions,
+			userAgent:        userAgent,
+			skipPreflight:    d.Get("skip_preflight").(bool),
+			warningWindow:    time.Duration(d.Get("token_expiry_warning_days").(int)) * 24 * time.Hour,
+		}
+
+		apiClient, clientDiags := clientConfig.newClient("", d.Get, true)
+		diags = append(diags, clientDiags...)
+		if diags.HasError() {
+			return nil, diags
+		}
+
+		for _, v := range d.Get("instances").([]interface{}) {
+			instance := v.(map[string]interface{})
+			name := instance["name"].(string)
+
+			if _, ok := apiClient.Instances[name]; ok {
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Error,
+					Summary:  "Duplicate instance",
+					Detail:   fmt.Sprintf("The instance %q is configured more than once.", name),
+				})
+				return nil, diags
+			}
+
+			get := func(k string) interface{} {
+				return instance[k]
+			}
+
+			instanceClient, clientDiags := clientConfig.newClient(name, get, false)
+			diags = append(diags, clientDiags...)
+			if diags.HasError() {
+				return nil, diags
+			}
+
+			apiClient.Instances[name] = instanceClient
+		}
+
+		if v, ok := d.GetOk("mirror.0"); ok {
+			mirror := v.(map[string]interface{})
+
+			get := func(k string) interface{} {
+				return mirror[k]
+			}
+
+			// the mirror doesn't share settings like freeze windows, they apply to the original request,
+			// but its requests are recorded in the audit log as well
+			mirrorConfig := clientConfig
+			mirrorConfig.settings = &Settings{
+				ConsistencyTimeout: settings.ConsistencyTimeout,
+				SensitivePII:       settings.SensitivePII,
+				AuditLog:           settings.AuditLog,
+			}
+			mirrorConfig.checkWarningsOnly = mirror["mode"].(string) == MirrorModeWarn
+
+			mirrorClient, clientDiags := mirrorConfig.newClient("", get, false)
+			for i := range clientDiags {
+				clientDiags[i].Detail = fmt.Sprintf("Mirror: %v", clientDiags[i].Detail)
+			}
+			diags = append(diags, clientDiags...)
+			if diags.HasError() {
+				return nil, diags
+			}
+
+			m, err := NewMirror(mirrorClient, mirror["mode"].(string), mirror["mapping_file"].(string))
+			if err != nil {
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Error,
+					Summary:  "Invalid mirror configuration",
+					Detail:   err.Error(),
+				})
+				return nil, diags
+			}
+			apiClient.Mirror = m
 		}
 
 		return apiClient, diags
 	}
 }
+
+// clientConfig holds everything shared by the clients of all instances.
+type clientConfig struct {
+	settings         *Settings
+	transportOptions TransportOptions
+	userAgent        string
+	skipPreflight    bool
+	warningWindow    time.Duration
+
+	// report failed preflight and token expiry checks as warnings, e.g. for a mirror in warn mode
+	checkWarningsOnly bool
+}
+
+// checked returns the diagnostics of preflight and token expiry checks, with errors turned into warnings
+// if checkWarningsOnly is set.
+func (cc clientConfig) checked(diags diag.Diagnostics) diag.Diagnostics {
+	if !cc.checkWarningsOnly {
+		return diags
+	}
+	for i := range diags {
+		diags[i].Severity = diag.Warning
+	}
+	return diags
+}
+
+// newClient creates and checks the client of an instance, whose endpoint and token are read by get.
+// The environment variables for tokens are only considered for the default instance.
+func (cc clientConfig) newClient(name string, get func(string) interface{}, useEnv bool) (*APIClient, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	// diagnostics of instances name the instance they belong to
+	withInstance := func(clientDiags diag.Diagnostics) diag.Diagnostics {
+		if name == "" {
+			return clientDiags
+		}
+		for i := range clientDiags {
+			clientDiags[i].Detail = fmt.Sprintf("Instance %q: %v", name, clientDiags[i].Detail)
+		}
+		return clientDiags
+	}
+
+	endpoint, endpointDiags := resolveEndpoint(get)
+	diags = append(diags, withInstance(endpointDiags)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	tokenSource, tokenDiags := resolveTokenSource(get, useEnv)
+	diags = append(diags, withInstance(tokenDiags)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	// fail early if e.g. the token file is missing or the token command fails
+	token, err := tokenSource.Token()
+	if err != nil {
+		diags = append(diags, withInstance(diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Unable to obtain token",
+			Detail:   err.Error(),
+		}})...)
+		return nil, diags
+	}
+
+	expiry := token.Expiry
+	if expiresAt, _ := get("token_expires_at").(string); expiresAt != "" {
+		// already checked by validation.IsRFC3339Time
+		expiry, _ = time.Parse(time.RFC3339, expiresAt)
+	}
+
+	diags = append(diags, withInstance(cc.checked(tokenExpiryDiagnostics(expiry, cc.warningWindow, time.Now())))...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	apiClient, err := NewClient(endpoint, tokenSource, cc.userAgent)
+	if err != nil {
+		diags = append(diags, withInstance(diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "Unable to create SCIM client",
+			Detail:   err.Error(),
+		}})...)
+		return nil, diags
+	}
+
+	apiClient.Settings = cc.settings
+
+	// already checked in configure
+	transport, _ := NewTransport(cc.transportOptions)
+	apiClient.SetTransport(transport)
+
+	if !cc.skipPreflight {
+		diags = append(diags, withInstance(cc.checked(preflight(apiClient)))...)
+		diags = append(diags, withInstance(apiClient.drainDiagnostics())...)
+	}
+
+	return apiClient, diags
+}
+
+// resolveEndpoint returns the configured endpoint, or builds it from region and tenant_id.
+func resolveEndpoint(get func(string) interface{}) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	endpoint, _ := get("endpoint").(string)
+	region, _ := get("region").(string)
+	tenantID, _ := get("tenant_id").(string)
+
+	switch {
+	case endpoint != "" && (region != "" || tenantID != ""):
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Conflicting endpoint configuration",
+			Detail:   "Either endpoint or region and tenant_id can be configured, not both.",
+		})
+	case endpoint != "":
+		return endpoint, diags
+	case region == "" || tenantID == "":
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Missing endpoint configuration",
+			Detail:   "Either endpoint or both region and tenant_id are required.",
+		})
+	default:
+		built, err := buildEndpoint(region, tenantID)
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Invalid endpoint configuration",
+				Detail:   err.Error(),
+			})
+		}
+		return built, diags
+	}
+
+	return "", diags
+}
+
+const tokenPrecedenceDescription = "If several token sources are configured, the first one of `token`, `tokens`, `token_file`, `token_command`, `AWS_SSO_SCIM_TOKEN`, `AWS_SSO_SCIM_TOKEN_FILE` and `AWS_SSO_SCIM_TOKEN_COMMAND` is used."
+
+// resolveTokenSource returns the token source with the highest precedence.
+// Arguments configured in the provider block always take precedence over environment variables.
+func resolveTokenSource(get func(string) interface{}, useEnv bool) (TokenSource, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	token, _ := get("token").(string)
+	tokens, _ := get("tokens").([]interface{})
+	tokenFile, _ := get("token_file").(string)
+	tokenCommand, _ := get("token_command").(string)
+
+	switch {
+	case token != "":
+		return NewStaticTokenSource(token), diags
+	case len(tokens) > 0:
+		return NewMultiTokenSource(expandStringList(tokens)), diags
+	case tokenFile != "":
+		return NewFileTokenSource(tokenFile), diags
+	case tokenCommand != "":
+		return NewCommandTokenSource(tokenCommand), diags
+	case !useEnv:
+	case os.Getenv("AWS_SSO_SCIM_TOKEN") != "":
+		return NewStaticTokenSource(os.Getenv("AWS_SSO_SCIM_TOKEN")), diags
+	case os.Getenv("AWS_SSO_SCIM_TOKEN_FILE") != "":
+		return NewFileTokenSource(os.Getenv("AWS_SSO_SCIM_TOKEN_FILE")), diags
+	case os.Getenv("AWS_SSO_SCIM_TOKEN_COMMAND") != "":
+		return NewCommandTokenSource(os.Getenv("AWS_SSO_SCIM_TOKEN_COMMAND")), diags
+	}
+
+	diags = append(diags, diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  "Missing token configuration",
+		Detail:   "One of token, tokens, token_file or token_command is required.",
+	})
+
+	return nil, diags
+}
+
+// withClientDiagnostics runs the CRUD functions of r with their own copy of the client, and appends the
+// diagnostics it collected, e.g. warnings about rejected tokens, to the diagnostics they return. Diagnostics
+// collected outside of resource operations are appended as well.
+func withClientDiagnostics(r *schema.Resource) {
+	type crudFunc = func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics
+
+	wrap := func(f crudFunc) crudFunc {
+		if f == nil {
+			return nil
+		}
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			client, ok := meta.(*APIClient)
+			if !ok {
+				return f(ctx, d, meta)
+			}
+
+			op := client.forOperation()
+			diags := f(ctx, d, op)
+			diags = append(diags, op.drainDiagnostics()...)
+			return append(diags, client.drainDiagnostics()...)
+		}
+	}
+
+	r.CreateContext = wrap(r.CreateContext)
+	r.ReadContext = wrap(r.ReadContext)
+	r.UpdateContext = wrap(r.UpdateContext)
+	r.DeleteContext = wrap(r.DeleteContext)
+}
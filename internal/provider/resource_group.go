@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -16,6 +17,7 @@ func resourceGroup() *schema.Resource {
 		ReadContext:   resourceGroupRead,
 		UpdateContext: resourceGroupUpdate,
 		DeleteContext: resourceGroupDelete,
+		CustomizeDiff: resourceGroupCustomizeDiff,
 		Importer: &schema.ResourceImporter{
 			StateContext: schema.ImportStatePassthroughContext,
 		},
@@ -40,7 +42,10 @@ func resourceGroup() *schema.Resource {
 }
 
 func resourceGroupCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
 	new_group := Group{
@@ -65,16 +70,18 @@ func resourceGroupCreate(ctx context.Context, d *schema.ResourceData, meta inter
 }
 
 func resourceGroupRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
 	group, resp, err := client.ReadGroup(d.Id())
 
 	if err != nil {
 		// if we get a 404, group maybe has vanished, so we remove this resource from the state.
-		if resp.StatusCode == 404 {
-			d.SetId("")
-			return diags
+		if resp != nil && resp.StatusCode == 404 {
+			return handleDrift(client, d, fmt.Sprintf("Group %q", d.Get("display_name")))
 		}
 
 		diags = append(diags, diag.Diagnostic{
@@ -86,13 +93,16 @@ func resourceGroupRead(ctx context.Context, d *schema.ResourceData, meta interfa
 	}
 
 	d.Set("display_name", group.DisplayName)
-	d.Set("external_id", group.ExternalID)
+	d.Set("external_id", client.unstampExternalID(group.ExternalID))
 
 	return diags
 }
 
 func resourceGroupUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
 	group, _, err := client.ReadGroup(d.Id())
@@ -108,6 +118,9 @@ func resourceGroupUpdate(ctx context.Context, d *schema.ResourceData, meta inter
 
 	group.DisplayName = d.Get("display_name").(string)
 	group.ExternalID = d.Get("external_id").(string)
+	if group.ExternalID != "" {
+		group.ExternalID = client.stampExternalID(group.ExternalID)
+	}
 
 	opmsg := OperationMessage{
 		Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
@@ -143,10 +156,13 @@ func resourceGroupUpdate(ctx context.Context, d *schema.ResourceData, meta inter
 }
 
 func resourceGroupDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
-	_, err := client.DeleteGroup(d.Get("id").(string))
+	_, err = client.DeleteGroup(d.Get("id").(string))
 
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
@@ -157,5 +173,17 @@ func resourceGroupDelete(ctx context.Context, d *schema.ResourceData, meta inter
 		return diags
 	}
 
-	return resourceGroupRead(ctx, d, meta)
+	d.SetId("")
+
+	return diags
+}
+
+// resourceGroupCustomizeDiff checks the planned group against the provider's policy.
+func resourceGroupCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	client, ok := meta.(*APIClient)
+	if !ok {
+		return nil
+	}
+
+	return client.Policy.checkGroupPolicy(d)
 }
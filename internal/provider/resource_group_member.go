@@ -48,10 +48,13 @@ func resourceGroupMember() *schema.Resource {
 }
 
 func resourceGroupMemberCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
-	_, err := client.AddGroupMember(d.Get("group_id").(string), d.Get("user_id").(string))
+	_, err = client.AddGroupMember(d.Get("group_id").(string), d.Get("user_id").(string))
 
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
@@ -68,7 +71,10 @@ func resourceGroupMemberCreate(ctx context.Context, d *schema.ResourceData, meta
 }
 
 func resourceGroupMemberRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
 	is_member, resp, err := client.TestGroupMember(d.Get("group_id").(string), d.Get("user_id").(string))
@@ -76,8 +82,7 @@ func resourceGroupMemberRead(ctx context.Context, d *schema.ResourceData, meta i
 	if err != nil {
 		// if we get a 404, user might have vanished, so we remove this resource from the state.
 		if resp != nil && resp.StatusCode == 404 {
-			d.SetId("")
-			return diags
+			return handleDrift(client, d, fmt.Sprintf("Membership of user %v in group %v", d.Get("user_id"), d.Get("group_id")))
 		}
 
 		diags = append(diags, diag.Diagnostic{
@@ -92,17 +97,20 @@ func resourceGroupMemberRead(ctx context.Context, d *schema.ResourceData, meta i
 		d.SetId(fmt.Sprintf("%v,%v", d.Get("group_id"), d.Get("user_id")))
 	} else {
 		// if not is_member, the association has been lost, so we remove this resource from the state.
-		d.SetId("")
+		return handleDrift(client, d, fmt.Sprintf("Membership of user %v in group %v", d.Get("user_id"), d.Get("group_id")))
 	}
 
 	return diags
 }
 
 func resourceGroupMemberDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
-	_, err := client.RemoveGroupMember(d.Get("group_id").(string), d.Get("user_id").(string))
+	_, err = client.RemoveGroupMember(d.Get("group_id").(string), d.Get("user_id").(string))
 
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
@@ -113,5 +121,7 @@ func resourceGroupMemberDelete(ctx context.Context, d *schema.ResourceData, meta
 		return diags
 	}
 
-	return resourceGroupMemberRead(ctx, d, meta)
+	d.SetId("")
+
+	return diags
 }
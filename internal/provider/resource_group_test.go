@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -66,3 +67,79 @@ resource "aws-sso-scim_group" "external_group" {
   external_id  = "e5a41517-bcd6-4b8b-8590-487ae996de44"
 }
 `
+
+func TestAccResourceGroupPolicy(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceGroupPolicy,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("violates policy rule group_display_name_pattern"),
+			},
+		},
+	})
+}
+
+const testAccResourceGroupPolicy = `
+provider "aws-sso-scim" {
+  policy {
+    group_display_name_pattern = "^aws-(prod|dev)-[a-z0-9-]+$"
+  }
+}
+
+resource "aws-sso-scim_group" "foo" {
+  display_name = "terraform-test-temporary-group"
+}
+`
+
+func TestAccResourceGroupReadOnly(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceGroupReadOnly,
+				ExpectError: regexp.MustCompile("read_only is enabled, refusing to create aws-sso-scim_group"),
+			},
+		},
+	})
+}
+
+const testAccResourceGroupReadOnly = `
+provider "aws-sso-scim" {
+  read_only = true
+}
+
+resource "aws-sso-scim_group" "foo" {
+  display_name = "terraform-test-temporary-read-only-group"
+}
+`
+
+func TestAccResourceGroupFreezeWindow(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceGroupFreezeWindow,
+				ExpectError: regexp.MustCompile(`change freeze "test" is in effect until 2100-01-01T00:00:00Z, refusing to create aws-sso-scim_group`),
+			},
+		},
+	})
+}
+
+const testAccResourceGroupFreezeWindow = `
+provider "aws-sso-scim" {
+  freeze_windows {
+    name  = "test"
+    start = "2000-01-01T00:00:00Z"
+    end   = "2100-01-01T00:00:00Z"
+  }
+}
+
+resource "aws-sso-scim_group" "foo" {
+  display_name = "terraform-test-temporary-frozen-group"
+}
+`
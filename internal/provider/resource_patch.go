@@ -0,0 +1,305 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/id"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourcePatch() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Applies PATCH operations to an existing user or group and reverts them on destroy.",
+
+		CreateContext: resourcePatchCreate,
+		ReadContext:   resourcePatchRead,
+		DeleteContext: resourcePatchDelete,
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"resource_type": {
+				Description:  "Type of the patched resource, either `Users` or `Groups`.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"Users", "Groups"}, false),
+			},
+			"resource_id": {
+				Description: "Identifier of the patched user or group.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+			},
+			"operations": {
+				Description:      "JSON encoded list of SCIM PATCH operations, each with `op`, `path` and `value`.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+			"inverse_operations": {
+				Description:      "JSON encoded list of SCIM PATCH operations applied on destroy. Derived from the state of the resource before patching if not given.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+		},
+	}
+}
+
+func resourcePatchCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	diags := diag.Diagnostics{}
+
+	resource_type := d.Get("resource_type").(string)
+	resource_id := d.Get("resource_id").(string)
+
+	var operations []Operation
+	if err := json.Unmarshal([]byte(d.Get("operations").(string)), &operations); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if d.Get("inverse_operations").(string) == "" {
+		current, _, err := client.ReadResource(resource_type, resource_id)
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Unable to read patched resource",
+				Detail:   err.Error(),
+			})
+			return diags
+		}
+
+		inverse, err := inverseOperations(current, operations)
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Unable to derive inverse operations",
+				Detail:   fmt.Sprintf("%v, please set inverse_operations explicitly", err),
+			})
+			return diags
+		}
+
+		inverse_json, err := json.Marshal(inverse)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.Set("inverse_operations", string(inverse_json))
+	}
+
+	opmsg := OperationMessage{
+		Schemas:    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		Operations: operations,
+	}
+
+	_, _, err = client.PatchResource(resource_type, &opmsg, resource_id)
+
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to apply Patch",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	d.SetId(id.UniqueId())
+
+	return resourcePatchRead(ctx, d, meta)
+}
+
+func resourcePatchRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	diags := diag.Diagnostics{}
+
+	_, resp, err := client.ReadResource(d.Get("resource_type").(string), d.Get("resource_id").(string))
+
+	if err != nil {
+		// if we get a 404, the patched resource has vanished, so we remove this resource from the state.
+		if resp != nil && resp.StatusCode == 404 {
+			return handleDrift(client, d, fmt.Sprintf("Patched %v %v", d.Get("resource_type"), d.Get("resource_id")))
+		}
+
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to read patched resource",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	return diags
+}
+
+func resourcePatchDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	diags := diag.Diagnostics{}
+
+	var operations []Operation
+	if err := json.Unmarshal([]byte(d.Get("inverse_operations").(string)), &operations); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if len(operations) == 0 {
+		return diags
+	}
+
+	opmsg := OperationMessage{
+		Schemas:    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		Operations: operations,
+	}
+
+	_, resp, err := client.PatchResource(d.Get("resource_type").(string), &opmsg, d.Get("resource_id").(string))
+
+	if err != nil {
+		// the patched resource is gone, so there is nothing left to revert
+		if resp != nil && resp.StatusCode == 404 {
+			return diags
+		}
+
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to revert Patch",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	return diags
+}
+
+// inverseOperations derives the operations which revert ops, given the state of the resource before they are applied.
+// The inverse operations are returned in reverse order.
+func inverseOperations(current map[string]interface{}, ops []Operation) ([]Operation, error) {
+	inverse := []Operation{}
+
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+
+		if strings.ContainsAny(op.Path, "[]") {
+			return nil, fmt.Errorf("path %q contains a filter", op.Path)
+		}
+
+		// add or remove of values of a multi-valued attribute, e.g. group members
+		_, valueIsList := op.Value.([]interface{})
+		old, exists := lookupAttribute(current, op.Path)
+		_, oldIsList := old.([]interface{})
+		if op.Path != "" && op.Value != nil && (valueIsList || oldIsList) {
+			switch strings.ToLower(op.Operation) {
+			case "add":
+				inverse = append(inverse, Operation{Operation: "remove", Path: op.Path, Value: op.Value})
+				continue
+			case "remove":
+				inverse = append(inverse, Operation{Operation: "add", Path: op.Path, Value: op.Value})
+				continue
+			}
+		}
+
+		switch strings.ToLower(op.Operation) {
+		case "add", "replace", "remove":
+		default:
+			return nil, fmt.Errorf("unknown operation %q", op.Operation)
+		}
+
+		// without a path, value holds the attributes to be changed
+		if op.Path == "" {
+			values, ok := op.Value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("operation %q without path requires an object value", op.Operation)
+			}
+
+			for k := range values {
+				inverse = append(inverse, restoreAttribute(current, k))
+			}
+			continue
+		}
+
+		if !exists && strings.ToLower(op.Operation) == "remove" {
+			continue
+		}
+
+		inverse = append(inverse, restoreAttribute(current, op.Path))
+	}
+
+	return inverse, nil
+}
+
+// restoreAttribute returns the operation which sets path back to its value in current.
+func restoreAttribute(current map[string]interface{}, path string) Operation {
+	old, exists := lookupAttribute(current, path)
+	if !exists {
+		return Operation{Operation: "remove", Path: path}
+	}
+
+	return Operation{Operation: "replace", Path: path, Value: old}
+}
+
+// lookupAttribute resolves an attribute path like "name.givenName" or
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department" within resource.
+// Attribute names are case insensitive.
+func lookupAttribute(resource map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		i := strings.LastIndex(path, ":")
+		extension, ok := lookupKey(resource, path[:i])
+		if !ok {
+			return nil, false
+		}
+		extensionAttributes, ok := extension.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		return lookupAttribute(extensionAttributes, path[i+1:])
+	}
+
+	var value interface{} = resource
+	for _, part := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = lookupKey(object, part)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+func lookupKey(object map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := object[key]; ok {
+		return v, true
+	}
+	for k, v := range object {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccResourcePatch(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourcePatch,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("aws-sso-scim_patch.foo", "id"),
+					resource.TestCheckResourceAttr("aws-sso-scim_patch.foo", "inverse_operations", `[{"op":"remove","path":"title"}]`),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourcePatch = `
+resource "aws-sso-scim_user" "foo" {
+  display_name = "terraform-test-temporary-patch-user"
+  user_name    = "terraform-test-temporary-patch-user"
+  family_name  = "temporary-patch-user"
+  given_name   = "terraform-test"
+}
+
+resource "aws-sso-scim_patch" "foo" {
+  resource_type = "Users"
+  resource_id   = aws-sso-scim_user.foo.id
+  operations = jsonencode([
+    {
+      op    = "replace"
+      path  = "title"
+      value = "Engineer"
+    }
+  ])
+}
+`
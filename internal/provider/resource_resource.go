@@ -0,0 +1,300 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// attributes which are managed by the SCIM server and never part of the configured attributes
+var serverManagedAttributes = []string{"id", "meta", "schemas"}
+
+func resourceResource() *schema.Resource {
+	return &schema.Resource{
+		// This description is used by the documentation generator and the language server.
+		Description: "Manages an arbitrary SCIM resource, e.g. of a custom resource type not supported by the other resources.",
+
+		CreateContext: resourceResourceCreate,
+		ReadContext:   resourceResourceRead,
+		UpdateContext: resourceResourceUpdate,
+		DeleteContext: resourceResourceDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: func(ctx context.Context, data *schema.ResourceData, i interface{}) ([]*schema.ResourceData, error) {
+				parts := strings.Split(data.Id(), ",")
+				if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+					return nil, fmt.Errorf("unexpected format of ID (%q), expected ENDPOINT,ID", data.Id())
+				}
+
+				data.Set("endpoint", parts[0])
+				data.SetId(parts[1])
+
+				return []*schema.ResourceData{data}, nil
+			},
+		},
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"endpoint": {
+				Description: "Endpoint of the resource type relative to the SCIM endpoint, e.g. `Devices`.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+			},
+			"schemas": {
+				Description: "Schema URIs of the resource.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"attributes": {
+				Description:      "JSON encoded attributes of the resource. Only the attributes given here are compared against the SCIM server, attributes removed from here are removed on the server. After import, the first apply sets all attributes given here.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
+			},
+		},
+	}
+}
+
+func resourceResourceCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	diags := diag.Diagnostics{}
+
+	var new_resource map[string]interface{}
+	if err := json.Unmarshal([]byte(d.Get("attributes").(string)), &new_resource); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if schemas := d.Get("schemas").([]interface{}); len(schemas) > 0 {
+		new_resource["schemas"] = schemas
+	}
+
+	resource, _, err := client.CreateResource(d.Get("endpoint").(string), new_resource)
+
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to create Resource",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	id, _ := resource["id"].(string)
+	if id == "" {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to create Resource",
+			Detail:   "response of the SCIM server does not contain an id",
+		})
+		return diags
+	}
+
+	d.SetId(id)
+
+	return resourceResourceRead(ctx, d, meta)
+}
+
+func resourceResourceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	diags := diag.Diagnostics{}
+
+	resource, resp, err := client.ReadResource(d.Get("endpoint").(string), d.Id())
+
+	if err != nil {
+		// if we get a 404, resource maybe has vanished, so we remove this resource from the state.
+		if resp != nil && resp.StatusCode == 404 {
+			return handleDrift(client, d, fmt.Sprintf("Resource of %v", d.Get("endpoint")))
+		}
+
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to read Resource",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	if schemas, ok := resource["schemas"].([]interface{}); ok && len(d.Get("schemas").([]interface{})) > 0 {
+		d.Set("schemas", schemas)
+	}
+
+	for _, k := range serverManagedAttributes {
+		delete(resource, k)
+	}
+
+	// the ownership prefix stamped into the externalId of users and groups is not configured
+	if externalID, ok := resource["externalId"].(string); ok && isOwnedEndpoint(d.Get("endpoint").(string)) {
+		resource["externalId"] = client.unstampExternalID(externalID)
+	}
+
+	// the server returns all attributes of the resource, but only the configured ones are tracked, so
+	// attributes which were never configured are never removed. Without any configured attributes, e.g. on
+	// import, nothing is tracked and the first apply sets the configured ones.
+	var configured map[string]interface{}
+	if current := d.Get("attributes").(string); current != "" {
+		if err := json.Unmarshal([]byte(current), &configured); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	resource = projectAttributes(resource, configured)
+
+	attributes, err := json.Marshal(resource)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.Set("attributes", string(attributes))
+
+	return diags
+}
+
+func resourceResourceUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	diags := diag.Diagnostics{}
+
+	old_attributes, new_attributes := d.GetChange("attributes")
+
+	var old_resource, new_resource map[string]interface{}
+	if err := json.Unmarshal([]byte(old_attributes.(string)), &old_resource); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := json.Unmarshal([]byte(new_attributes.(string)), &new_resource); err != nil {
+		return diag.FromErr(err)
+	}
+
+	opmsg := OperationMessage{
+		Schemas:    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		Operations: diffAttributes(old_resource, new_resource),
+	}
+
+	if len(opmsg.Operations) == 0 {
+		return resourceResourceRead(ctx, d, meta)
+	}
+
+	for i, op := range opmsg.Operations {
+		if externalID, ok := op.Value.(string); ok && op.Path == "externalId" && isOwnedEndpoint(d.Get("endpoint").(string)) {
+			opmsg.Operations[i].Value = client.stampExternalID(externalID)
+		}
+	}
+
+	_, _, err = client.PatchResource(d.Get("endpoint").(string), &opmsg, d.Id())
+
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to update Resource",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	return resourceResourceRead(ctx, d, meta)
+}
+
+func resourceResourceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	diags := diag.Diagnostics{}
+
+	_, err = client.DeleteResource(d.Get("endpoint").(string), d.Id())
+
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Unable to delete Resource",
+			Detail:   err.Error(),
+		})
+		return diags
+	}
+
+	d.SetId("")
+
+	return diags
+}
+
+// projectAttributes returns the subset of actual which has keys in configured, descending into nested objects.
+func projectAttributes(actual map[string]interface{}, configured map[string]interface{}) map[string]interface{} {
+	projected := map[string]interface{}{}
+
+	for k, v := range configured {
+		actualValue, ok := actual[k]
+		if !ok {
+			continue
+		}
+
+		configuredObject, configuredIsObject := v.(map[string]interface{})
+		actualObject, actualIsObject := actualValue.(map[string]interface{})
+		if configuredIsObject && actualIsObject {
+			projected[k] = projectAttributes(actualObject, configuredObject)
+			continue
+		}
+
+		projected[k] = actualValue
+	}
+
+	return projected
+}
+
+// diffAttributes returns the PATCH operations needed to turn the top level attributes of old into new.
+// old are the attributes in the state, which only contains configured attributes, see resourceResourceRead.
+func diffAttributes(old map[string]interface{}, new map[string]interface{}) []Operation {
+	operations := []Operation{}
+
+	keys := make([]string, 0, len(old)+len(new))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		oldValue, inOld := old[k]
+		newValue, inNew := new[k]
+
+		switch {
+		case !inNew:
+			operations = append(operations, Operation{
+				Operation: "remove",
+				Path:      k,
+			})
+		case !inOld || !reflect.DeepEqual(oldValue, newValue):
+			operations = append(operations, Operation{
+				Operation: "replace",
+				Path:      k,
+				Value:     newValue,
+			})
+		}
+	}
+
+	return operations
+}
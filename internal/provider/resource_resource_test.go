@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccResourceResource(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceResource,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("aws-sso-scim_resource.foo", "id"),
+					resource.TestCheckResourceAttr("aws-sso-scim_resource.foo", "attributes", `{"displayName":"terraform-test-temporary-resource"}`),
+				),
+			},
+			{
+				Config: testAccResourceResourceUpdate,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("aws-sso-scim_resource.foo", "id"),
+					resource.TestCheckResourceAttr("aws-sso-scim_resource.foo", "attributes", `{"displayName":"terraform-test-temporary-resource2"}`),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceResource = `
+resource "aws-sso-scim_resource" "foo" {
+  endpoint   = "Groups"
+  attributes = jsonencode({
+    displayName = "terraform-test-temporary-resource"
+  })
+}
+`
+
+const testAccResourceResourceUpdate = `
+resource "aws-sso-scim_resource" "foo" {
+  endpoint   = "Groups"
+  attributes = jsonencode({
+    displayName = "terraform-test-temporary-resource2"
+  })
+}
+`
+
+func TestResourceResourceImportThenApply(t *testing.T) {
+	var patches []OperationMessage
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "GET":
+			fmt.Fprint(w, `{"id":"1","displayName":"g","externalId":"x","members":[{"value":"u-1"}]}`)
+		case "PATCH":
+			var opmsg OperationMessage
+			json.NewDecoder(r.Body).Decode(&opmsg)
+			patches = append(patches, opmsg)
+			fmt.Fprint(w, `{"id":"1"}`)
+		}
+	})
+
+	ctx := context.Background()
+	r := resourceResource()
+
+	// the state after import, see the importer
+	d := r.Data(&terraform.InstanceState{ID: "1", Attributes: map[string]string{"id": "1", "endpoint": "Groups"}})
+	if diags := resourceResourceRead(ctx, d, c); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if attributes := d.Get("attributes"); attributes != "{}" {
+		t.Errorf("expected no attributes to be tracked after import, got %v", attributes)
+	}
+
+	state := d.State()
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{"endpoint": "Groups", "attributes": `{"displayName":"g"}`})
+	diff, err := r.Diff(ctx, state, config, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err = schema.InternalMap(r.Schema).Data(state, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diags := resourceResourceUpdate(ctx, d, c); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	if len(patches) != 1 {
+		t.Fatalf("expected a single PATCH request, got %v", patches)
+	}
+	for _, op := range patches[0].Operations {
+		if op.Operation != "replace" || op.Path != "displayName" {
+			t.Errorf("expected only displayName to be replaced, got %v %v", op.Operation, op.Path)
+		}
+	}
+	if attributes := d.Get("attributes"); attributes != `{"displayName":"g"}` {
+		t.Errorf("expected the configured attributes to be tracked, got %v", attributes)
+	}
+}
+
+func TestDiffAttributes(t *testing.T) {
+	operations := diffAttributes(
+		map[string]interface{}{"a": "1", "b": "2", "c": "3"},
+		map[string]interface{}{"a": "1", "b": "4", "d": "5"},
+	)
+
+	expected := []Operation{
+		{Operation: "replace", Path: "b", Value: "4"},
+		{Operation: "remove", Path: "c"},
+		{Operation: "replace", Path: "d", Value: "5"},
+	}
+	if !reflect.DeepEqual(operations, expected) {
+		t.Errorf("expected %v, got %v", expected, operations)
+	}
+}
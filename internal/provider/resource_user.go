@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -16,6 +17,7 @@ func resourceUser() *schema.Resource {
 		ReadContext:   resourceUserRead,
 		DeleteContext: resourceUserDelete,
 		UpdateContext: resourceUserUpdate,
+		CustomizeDiff: resourceUserCustomizeDiff,
 		Importer: &schema.ResourceImporter{
 			StateContext: schema.ImportStatePassthroughContext,
 		},
@@ -51,22 +53,50 @@ func resourceUser() *schema.Resource {
 				Optional:    true,
 			},
 			"email_type": {
-				Description: "Usage type of the email adress, e.g. 'work'.",
+				Description: "Usage type of the email adress, e.g. 'work'. Defaults to `email_type` of the provider's `user_defaults`.",
 				Type:        schema.TypeString,
 				Optional:    true,
+				Computed:    true,
 			},
 			"active": {
-				Description: "Set user to be active. Defaults to `false`.",
+				Description: "Set user to be active. Defaults to `active` of the provider's `user_defaults`, which defaults to `false`.",
 				Type:        schema.TypeBool,
 				Optional:    true,
-				Default:     false,
+				Computed:    true,
+			},
+			"user_type": {
+				Description: "Type of the user, e.g. 'Employee'. Defaults to `user_type` of the provider's `user_defaults`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+			},
+			"locale": {
+				Description: "Locale of the user, e.g. 'en-US'. Defaults to `locale` of the provider's `user_defaults`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+			},
+			"timezone": {
+				Description: "Time zone of the user, e.g. 'Europe/Berlin'. Defaults to `timezone` of the provider's `user_defaults`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+			},
+			"preferred_language": {
+				Description: "Preferred language of the user, e.g. 'en'. Defaults to `preferred_language` of the provider's `user_defaults`.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
 			},
 		},
 	}
 }
 
 func resourceUserCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
 	new_user := User{
@@ -76,7 +106,11 @@ func resourceUserCreate(ctx context.Context, d *schema.ResourceData, meta interf
 			FamilyName: d.Get("family_name").(string),
 			GivenName:  d.Get("given_name").(string),
 		},
-		Active: d.Get("active").(bool),
+		Active:            d.Get("active").(bool),
+		UserType:          d.Get("user_type").(string),
+		Locale:            d.Get("locale").(string),
+		Timezone:          d.Get("timezone").(string),
+		PreferredLanguage: d.Get("preferred_language").(string),
 	}
 
 	if d.Get("email_address") != "" {
@@ -92,6 +126,8 @@ func resourceUserCreate(ctx context.Context, d *schema.ResourceData, meta interf
 		}
 	}
 
+	client.Normalization.NormalizeUser(&new_user)
+
 	user, _, err := client.CreateUser(&new_user)
 
 	if err != nil {
@@ -109,16 +145,18 @@ func resourceUserCreate(ctx context.Context, d *schema.ResourceData, meta interf
 }
 
 func resourceUserRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
 	user, resp, err := client.ReadUser(d.Id())
 
 	if err != nil {
 		// if we get a 404, user maybe has vanished, so we remove this resource from the state.
-		if resp.StatusCode == 404 {
-			d.SetId("")
-			return diags
+		if resp != nil && resp.StatusCode == 404 {
+			return handleDrift(client, d, fmt.Sprintf("User %q", d.Get("user_name")))
 		}
 
 		diags = append(diags, diag.Diagnostic{
@@ -134,6 +172,10 @@ func resourceUserRead(ctx context.Context, d *schema.ResourceData, meta interfac
 	d.Set("family_name", user.Name.FamilyName)
 	d.Set("given_name", user.Name.GivenName)
 	d.Set("active", user.Active)
+	d.Set("user_type", user.UserType)
+	d.Set("locale", user.Locale)
+	d.Set("timezone", user.Timezone)
+	d.Set("preferred_language", user.PreferredLanguage)
 
 	// AWS SSO SCIM only allows a single value for multi-valued properties like emails, so executes only once
 	for _, v := range user.Emails {
@@ -145,10 +187,13 @@ func resourceUserRead(ctx context.Context, d *schema.ResourceData, meta interfac
 }
 
 func resourceUserDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
-	_, err := client.DeleteUser(d.Get("id").(string))
+	_, err = client.DeleteUser(d.Get("id").(string))
 
 	if err != nil {
 		diags = append(diags, diag.Diagnostic{
@@ -159,11 +204,16 @@ func resourceUserDelete(ctx context.Context, d *schema.ResourceData, meta interf
 		return diags
 	}
 
-	return resourceUserRead(ctx, d, meta)
+	d.SetId("")
+
+	return diags
 }
 
 func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*APIClient)
+	client, err := clientFor(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	diags := diag.Diagnostics{}
 
 	user, resp, err := client.ReadUser(d.Id())
@@ -172,9 +222,8 @@ func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, meta interf
 
 	if err != nil {
 		// if we get a 404, user maybe has vanished, so we remove this resource from the state.
-		if resp.StatusCode == 404 {
-			d.SetId("")
-			return diags
+		if resp != nil && resp.StatusCode == 404 {
+			return handleDrift(client, d, fmt.Sprintf("User %q", d.Get("user_name")))
 		}
 
 		diags = append(diags, diag.Diagnostic{
@@ -190,6 +239,10 @@ func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, meta interf
 	user.Name.FamilyName = d.Get("family_name").(string)
 	user.Name.GivenName = d.Get("given_name").(string)
 	user.Active = d.Get("active").(bool)
+	user.UserType = d.Get("user_type").(string)
+	user.Locale = d.Get("locale").(string)
+	user.Timezone = d.Get("timezone").(string)
+	user.PreferredLanguage = d.Get("preferred_language").(string)
 
 	if d.Get("email_address") != "" {
 		user.Emails = []Email{
@@ -205,6 +258,8 @@ func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, meta interf
 		user.Emails = []Email{}
 	}
 
+	client.Normalization.NormalizeUser(user)
+
 	_, resp, err = client.PutUser(user, d.Id())
 
 	if err != nil {
@@ -218,3 +273,53 @@ func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, meta interf
 
 	return resourceUserRead(ctx, d, meta)
 }
+
+// UserDefaults are applied to users which don't configure the respective attribute.
+type UserDefaults struct {
+	EmailType         string
+	Active            bool
+	UserType          string
+	Locale            string
+	Timezone          string
+	PreferredLanguage string
+}
+
+// resourceUserCustomizeDiff merges the provider's user_defaults into the plan. The normalized values which are
+// actually written are checked against the provider's policy.
+func resourceUserCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	client, ok := meta.(*APIClient)
+	if !ok {
+		return nil
+	}
+
+	defaults := map[string]interface{}{
+		"active":             client.UserDefaults.Active,
+		"email_type":         client.UserDefaults.EmailType,
+		"user_type":          client.UserDefaults.UserType,
+		"locale":             client.UserDefaults.Locale,
+		"timezone":           client.UserDefaults.Timezone,
+		"preferred_language": client.UserDefaults.PreferredLanguage,
+	}
+
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+
+	for k, v := range defaults {
+		if !config.GetAttr(k).IsNull() {
+			continue
+		}
+
+		// without a default, the value of the SCIM server is kept
+		if v == "" {
+			continue
+		}
+
+		if err := d.SetNew(k, v); err != nil {
+			return err
+		}
+	}
+
+	return client.Policy.checkUserPolicy(d, client.Normalization)
+}
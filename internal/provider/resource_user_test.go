@@ -103,3 +103,75 @@ resource "aws-sso-scim_user" "foo" {
   active = false
 }
 `
+
+func TestAccResourceUserDefaults(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceUserDefaults,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("aws-sso-scim_user.foo", "id"),
+					resource.TestCheckResourceAttr("aws-sso-scim_user.foo", "active", "true"),
+					resource.TestCheckResourceAttr("aws-sso-scim_user.foo", "email_type", "work"),
+					resource.TestCheckResourceAttr("aws-sso-scim_user.foo", "locale", "de-DE"),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceUserDefaults = `
+provider "aws-sso-scim" {
+  user_defaults {
+    email_type = "work"
+    active     = true
+    locale     = "de-DE"
+  }
+}
+
+resource "aws-sso-scim_user" "foo" {
+  display_name = "terraform-test-temporary-user-defaults"
+  user_name = "terraform-test-temporary-user-defaults"
+  family_name = "temporary-user-defaults"
+  given_name = "terraform-test"
+	email_address = "terraformtest-defaults@burda-forward.de"
+}
+`
+
+func TestAccResourceUserNormalization(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceUserNormalization,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("aws-sso-scim_user.foo", "id"),
+					resource.TestCheckResourceAttr("aws-sso-scim_user.foo", "user_name", "terraform-test-temporary-user-normalized"),
+				),
+			},
+			{
+				// the mixed-case user_name does not differ from the lowercase one read back
+				Config:   testAccResourceUserNormalization,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+const testAccResourceUserNormalization = `
+provider "aws-sso-scim" {
+  normalization {
+    lowercase_user_name = true
+  }
+}
+
+resource "aws-sso-scim_user" "foo" {
+  display_name = "terraform-test-temporary-user-normalized"
+  user_name = "Terraform-Test-Temporary-User-Normalized"
+  family_name = "temporary-user-normalized"
+  given_name = "terraform-test"
+}
+`
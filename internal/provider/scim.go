@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -110,7 +111,7 @@ type GroupListResponse struct {
 
 type Operation struct {
 	Operation string      `json:"op"`
-	Value     interface{} `json:"value"`
+	Value     interface{} `json:"value,omitempty"`
 	Path      string      `json:"path,omitempty"`
 }
 
@@ -118,3 +119,48 @@ type OperationMessage struct {
 	Schemas    []string    `json:"schemas"`
 	Operations []Operation `json:"Operations"`
 }
+
+type BulkConfig struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations,omitempty"`
+	MaxPayloadSize int  `json:"maxPayloadSize,omitempty"`
+}
+
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type ServiceProviderConfig struct {
+	Schemas []string   `json:"schemas"`
+	Patch   Supported  `json:"patch"`
+	Bulk    BulkConfig `json:"bulk"`
+	Filter  Supported  `json:"filter"`
+}
+
+type BulkOperation struct {
+	Method  string      `json:"method"`
+	BulkID  string      `json:"bulkId,omitempty"`
+	Version string      `json:"version,omitempty"`
+	Path    string      `json:"path"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type BulkRequest struct {
+	Schemas      []string        `json:"schemas"`
+	FailOnErrors int             `json:"failOnErrors,omitempty"`
+	Operations   []BulkOperation `json:"Operations"`
+}
+
+type BulkOperationResponse struct {
+	Method   string          `json:"method"`
+	BulkID   string          `json:"bulkId,omitempty"`
+	Version  string          `json:"version,omitempty"`
+	Location string          `json:"location,omitempty"`
+	Status   string          `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+type BulkResponse struct {
+	Schemas    []string                `json:"schemas"`
+	Operations []BulkOperationResponse `json:"Operations"`
+}
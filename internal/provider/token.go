@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+const (
+	// Time out token commands after 30 seconds
+	TokenCommandTimeout int = 30
+
+	// Warn about expiring tokens 30 days in advance
+	DefaultTokenExpiryWarningDays int = 30
+)
+
+// Token is a bearer token, Expiry is zero if unknown.
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+// TokenSource provides the bearer token for requests to the SCIM endpoint.
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+type staticTokenSource struct {
+	token *Token
+}
+
+func NewStaticTokenSource(token string) TokenSource {
+	return &staticTokenSource{token: &Token{Value: token}}
+}
+
+func (s *staticTokenSource) Token() (*Token, error) {
+	if s.token.Value == "" {
+		return nil, errors.New("token is empty")
+	}
+	return s.token, nil
+}
+
+// fileTokenSource reads the token from a file and reads it again whenever the file changes.
+type fileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	token   *Token
+	modTime time.Time
+	size    int64
+}
+
+func NewFileTokenSource(path string) TokenSource {
+	return &fileTokenSource{path: path}
+}
+
+func (s *fileTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token file: %v", err)
+	}
+
+	if s.token != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.token, nil
+	}
+
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token file: %v", err)
+	}
+
+	value := strings.TrimSpace(string(content))
+	if value == "" {
+		return nil, fmt.Errorf("token file %q is empty", s.path)
+	}
+
+	s.token = &Token{Value: value}
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+
+	return s.token, nil
+}
+
+// commandTokenSource runs a command which prints the token, either as plain text or as JSON
+// like {"token": "...", "expires_at": "2024-01-01T00:00:00Z"}. The token is cached until it expires.
+type commandTokenSource struct {
+	command string
+
+	mu    sync.Mutex
+	token *Token
+}
+
+type tokenCommandOutput struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewCommandTokenSource(command string) TokenSource {
+	return &commandTokenSource{command: command}
+}
+
+func (s *commandTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && (s.token.Expiry.IsZero() || time.Now().Before(s.token.Expiry)) {
+		return s.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(TokenCommandTimeout)*time.Second)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", s.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", s.command)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("token command failed: %v %v", err, strings.TrimSpace(stderr.String()))
+	}
+
+	token, err := parseTokenCommandOutput(stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	s.token = token
+
+	return s.token, nil
+}
+
+func parseTokenCommandOutput(output []byte) (*Token, error) {
+	trimmed := bytes.TrimSpace(output)
+
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var parsed tokenCommandOutput
+		if err := json.Unmarshal(trimmed, &parsed); err != nil {
+			return nil, fmt.Errorf("unable to parse output of token command: %v", err)
+		}
+		if parsed.Token == "" {
+			return nil, errors.New("output of token command does not contain a token")
+		}
+		return &Token{Value: parsed.Token, Expiry: parsed.ExpiresAt}, nil
+	}
+
+	if len(trimmed) == 0 {
+		return nil, errors.New("output of token command is empty")
+	}
+
+	return &Token{Value: string(trimmed)}, nil
+}
+
+// multiTokenSource holds an ordered list of tokens and sticks to the first one which is accepted.
+type multiTokenSource struct {
+	mu      sync.Mutex
+	tokens  []*Token
+	current int
+}
+
+func NewMultiTokenSource(tokens []string) TokenSource {
+	s := &multiTokenSource{}
+	for _, t := range tokens {
+		s.tokens = append(s.tokens, &Token{Value: t})
+	}
+	return s
+}
+
+func (s *multiTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.tokens) == 0 || s.tokens[s.current].Value == "" {
+		return nil, errors.New("token is empty")
+	}
+	return s.tokens[s.current], nil
+}
+
+// Failover switches to the next token after dead has been rejected. It returns the indexes of the
+// dead and the next token, and false if there is no token left to try.
+func (s *multiTokenSource) Failover(dead *Token) (int, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadIndex := -1
+	for i, t := range s.tokens {
+		if t == dead {
+			deadIndex = i
+		}
+	}
+
+	// another request already switched to a later token
+	if deadIndex >= 0 && deadIndex < s.current {
+		return deadIndex, s.current, true
+	}
+
+	if deadIndex < 0 || deadIndex+1 >= len(s.tokens) {
+		return deadIndex, s.current, false
+	}
+
+	s.current = deadIndex + 1
+
+	return deadIndex, s.current, true
+}
+
+// tokenExpiryDiagnostics warns about a token expiring within window and fails for an expired token.
+// A zero expiry means the expiry is unknown.
+func tokenExpiryDiagnostics(expiry time.Time, window time.Duration, now time.Time) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	switch {
+	case expiry.IsZero():
+	case !now.Before(expiry):
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Authentication token expired",
+			Detail:   fmt.Sprintf("The authentication token expired at %v. Please generate a new access token in the AWS SSO console.", expiry.Format(time.RFC3339)),
+		})
+	case expiry.Sub(now) <= window:
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "Authentication token expires soon",
+			Detail:   fmt.Sprintf("The authentication token expires at %v, in %v days. Please rotate it before.", expiry.Format(time.RFC3339), int(expiry.Sub(now).Hours()/24)),
+		})
+	}
+
+	return diags
+}
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMultiTokenSourceFailover(t *testing.T) {
+	s := NewMultiTokenSource([]string{"a", "b", "c"}).(*multiTokenSource)
+	a, _ := s.Token()
+
+	if dead, next, ok := s.Failover(a); !ok || dead != 0 || next != 1 {
+		t.Errorf("expected failover from 0 to 1, got %v, %v, %v", dead, next, ok)
+	}
+
+	// another request rejected with a still uses the next token, without skipping b
+	if dead, next, ok := s.Failover(a); !ok || dead != 0 || next != 1 {
+		t.Errorf("expected the switch of another request to be used, got %v, %v, %v", dead, next, ok)
+	}
+
+	b, _ := s.Token()
+	if b.Value != "b" {
+		t.Fatalf("expected token b, got %v", b.Value)
+	}
+	s.Failover(b)
+
+	c, _ := s.Token()
+	if dead, _, ok := s.Failover(c); ok || dead != 2 {
+		t.Errorf("expected no failover from the last token, got %v, %v", dead, ok)
+	}
+	if current, _ := s.Token(); current.Value != "c" {
+		t.Errorf("expected to stick to the last token, got %v", current.Value)
+	}
+}
+
+// tokenServer answers requests with 401 unless they carry the accepted token.
+func tokenServer(accepted string) (*[]string, http.HandlerFunc) {
+	var mu sync.Mutex
+	tokens := []string{}
+
+	return &tokens, func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		mu.Lock()
+		tokens = append(tokens, token)
+		mu.Unlock()
+
+		if token != accepted {
+			w.WriteHeader(401)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","userName":"jdoe"}`)
+	}
+}
+
+func TestRequestsFailOverToTheNextToken(t *testing.T) {
+	tokens, handler := tokenServer("b")
+	c := newTestClient(t, handler)
+	c.TokenSource = NewMultiTokenSource([]string{"a", "b"})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := c.ReadUser("1")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected all requests to succeed with the next token, got %v", err)
+		}
+	}
+
+	rejected := 0
+	for _, token := range *tokens {
+		if token == "a" {
+			rejected++
+		}
+	}
+	if rejected == 0 || len(*tokens) != 10+rejected {
+		t.Errorf("expected each rejected request to be retried once, got %v", *tokens)
+	}
+
+	diags := c.drainDiagnostics()
+	if len(diags) != 1 || !strings.Contains(diags[0].Detail, "index 0") {
+		t.Errorf("expected a single warning about the rejected token, got %v", diags)
+	}
+
+	// later requests use the accepted token right away
+	c.ReadUser("1")
+	if last := (*tokens)[len(*tokens)-1]; last != "b" || len(*tokens) != 11+rejected {
+		t.Errorf("expected a single request with token b, got %v", *tokens)
+	}
+}
+
+func TestRequestsFailWhenTheLastTokenIsRejected(t *testing.T) {
+	tokens, handler := tokenServer("c")
+	c := newTestClient(t, handler)
+	c.TokenSource = NewMultiTokenSource([]string{"a", "b"})
+
+	if _, resp, err := c.ReadUser("1"); err == nil || resp.StatusCode != 401 {
+		t.Fatalf("expected the request to be rejected, got %v", err)
+	}
+	if strings.Join(*tokens, ",") != "a,b" {
+		t.Errorf("expected the request to be retried once with the next token, got %v", *tokens)
+	}
+
+	if _, resp, err := c.ReadUser("1"); err == nil || resp.StatusCode != 401 {
+		t.Fatalf("expected the request to be rejected, got %v", err)
+	}
+	if strings.Join(*tokens, ",") != "a,b,b" {
+		t.Errorf("expected the last token not to be retried, got %v", *tokens)
+	}
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// TransportOptions configure how the client connects to the SCIM endpoint.
+type TransportOptions struct {
+	HTTPProxy          string
+	NoProxy            string
+	CABundleFile       string
+	CABundle           string
+	ClientCert         string
+	ClientKey          string
+	InsecureSkipVerify bool
+}
+
+// NewTransport returns a transport using the given proxy, CA bundle and client certificate.
+// Proxy settings which are not configured are taken from the HTTPS_PROXY and NO_PROXY environment variables.
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.HTTPProxy != "" || opts.NoProxy != "" {
+		proxyConfig := httpproxy.FromEnvironment()
+
+		if opts.HTTPProxy != "" {
+			if _, err := url.Parse(opts.HTTPProxy); err != nil {
+				return nil, fmt.Errorf("invalid http_proxy %q: %v", opts.HTTPProxy, err)
+			}
+			proxyConfig.HTTPProxy = opts.HTTPProxy
+			proxyConfig.HTTPSProxy = opts.HTTPProxy
+		}
+
+		if opts.NoProxy != "" {
+			proxyConfig.NoProxy = opts.NoProxy
+		}
+
+		proxyFunc := proxyConfig.ProxyFunc()
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CABundleFile != "" || opts.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if opts.CABundleFile != "" {
+			pem, err := os.ReadFile(opts.CABundleFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read ca_bundle_file: %v", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in ca_bundle_file %q", opts.CABundleFile)
+			}
+		}
+
+		if opts.CABundle != "" && !pool.AppendCertsFromPEM([]byte(opts.CABundle)) {
+			return nil, errors.New("no certificates found in ca_bundle")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, errors.New("client_cert and client_key have to be configured together")
+		}
+
+		cert, err := tls.X509KeyPair([]byte(opts.ClientCert), []byte(opts.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+func (c *APIClient) SetTransport(transport http.RoundTripper) {
+	c.httpClient.client.Transport = transport
+}
@@ -45,4 +45,7 @@ func main() {
 	}
 
 	plugin.Serve(opts)
+
+	// events are delivered in the background, so some may still be queued
+	provider.FlushEvents(provider.EventFlushTimeout)
 }